@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,19 +19,45 @@ const (
 )
 
 type IbClient struct {
-	ServerVersion    int        // IB server version
-	ServerTime       time.Time  // IB server time
-	NextValidOrderId int        // next valid order id
-	ManagedAccounts  string     // Ids of managed accounts
-	MessageBus       MessageBus // bus used to communicate with server
-
-	currentRequestId int                   // used to generate sequence of request Ids
-	channels         map[int]chan []string // message exchange
+	ServerVersion int       // IB server version
+	ServerTime    time.Time // IB server time
+	// NextValidOrderId is the next valid order id, kept in sync with NextOrderId.
+	//
+	// Deprecated: use NextOrderId, which is safe for concurrent use.
+	NextValidOrderId int
+	// ManagedAccounts are the comma separated ids of the managed accounts, kept in sync with Accounts.
+	//
+	// Deprecated: use Accounts, which is safe for concurrent use.
+	ManagedAccounts string
+	MessageBus      MessageBus // bus used to communicate with server
+
+	clientId         int                 // id of this API client
+	currentRequestId int                 // used to generate sequence of request Ids
+	nextOrderId      int                 // next order id handed out by NextOrderId
+	orderIds         map[int]bool        // ids of the orders placed or cancelled by this client, their errors go to the order listeners
+	accounts         []string            // ids of the managed accounts
+	channels         map[int]*listener   // message exchange, keyed by request id
+	listeners        map[int][]*listener // receivers of messages not tied to a request, keyed by message id
+	positionsCount   int                 // active position subscriptions, sharing the single subscription of the client
+	updatesAccount   string              // account of the active account updates subscription
+	updatesCount     int                 // active account updates subscriptions, sharing the single subscription of the client
 	ready            chan struct{}
+	readyOnce        sync.Once
+
+	components map[string][]SmartComponent // SMART components cache, keyed by BBO exchange
 
 	mu                   sync.Mutex
 	requestIdMutex       sync.Mutex
+	orderIdMutex         sync.Mutex
 	contractDetailsMutex sync.Mutex
+	completedOrdersMutex sync.Mutex
+	listenersMutex       sync.Mutex
+	positionsMutex       sync.Mutex
+	accountUpdatesMutex  sync.Mutex
+	faMutex              sync.Mutex
+	accountsMutex        sync.Mutex
+	depthExchangesMutex  sync.Mutex
+	componentsMutex      sync.Mutex
 }
 
 type MessageBus interface {
@@ -53,7 +81,9 @@ func Connect(host string, port int, clientId int) (*IbClient, error) {
 
 	client := IbClient{
 		MessageBus: &bus,
-		channels:   make(map[int]chan []string),
+		clientId:   clientId,
+		channels:   make(map[int]*listener),
+		listeners:  make(map[int][]*listener),
 	}
 
 	if err := client.handshake(); err != nil {
@@ -125,6 +155,86 @@ func (c *IbClient) nextRequestId() int {
 	return tmp + 9000
 }
 
+// NextOrderId returns the next order id to use with PlaceOrder. It is safe to call from multiple goroutines.
+// The sequence is seeded with the next valid id reported by TWS on connection and whenever TWS reports it again.
+func (c *IbClient) NextOrderId() int {
+	c.orderIdMutex.Lock()
+	defer c.orderIdMutex.Unlock()
+
+	orderId := c.nextOrderId
+	c.nextOrderId++
+	c.NextValidOrderId = c.nextOrderId
+
+	return orderId
+}
+
+// nextOrderIds reserves count consecutive order ids and returns the first one.
+func (c *IbClient) nextOrderIds(count int) int {
+	c.orderIdMutex.Lock()
+	defer c.orderIdMutex.Unlock()
+
+	orderId := c.nextOrderId
+	c.nextOrderId += count
+	c.NextValidOrderId = c.nextOrderId
+
+	return orderId
+}
+
+// seedOrderId moves the order id sequence forward to the next valid id reported by TWS.
+// Ids already handed out are never reused.
+func (c *IbClient) seedOrderId(orderId int) {
+	c.orderIdMutex.Lock()
+	defer c.orderIdMutex.Unlock()
+
+	if orderId > c.nextOrderId {
+		c.nextOrderId = orderId
+		c.NextValidOrderId = orderId
+	}
+}
+
+// trackOrderId records an order id sent to TWS, so that its errors are not mistaken for those of a request with the same id.
+func (c *IbClient) trackOrderId(orderId int) {
+	c.orderIdMutex.Lock()
+	defer c.orderIdMutex.Unlock()
+
+	if c.orderIds == nil {
+		c.orderIds = make(map[int]bool)
+	}
+	c.orderIds[orderId] = true
+}
+
+// isOrderId reports whether an id belongs to an order sent by this client.
+func (c *IbClient) isOrderId(id int) bool {
+	c.orderIdMutex.Lock()
+	defer c.orderIdMutex.Unlock()
+
+	return c.orderIds[id]
+}
+
+// RefreshOrderId requests the next valid order id from TWS and re-seeds the sequence returned by NextOrderId.
+// Use it when orders may have been placed by other sessions of the same client id.
+func (c *IbClient) RefreshOrderId(ctx context.Context) error {
+	messages := c.addListener(nextValidId)
+	defer c.removeListener(messages)
+
+	message := messageBuilder{}
+	message.addInt(requestIds)
+	message.addInt(1) // version
+	message.addInt(1) // number of ids, ignored by TWS
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return fmt.Errorf("error sending request for ids: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("order id request cancelled: %w", ctx.Err())
+	case <-messages:
+		// the sequence was re-seeded when the message was received
+		return nil
+	}
+}
+
 func (c *IbClient) readFields() ([]string, error) {
 	data, err := c.MessageBus.ReadPacket()
 	if err != nil {
@@ -178,59 +288,201 @@ func (c *IbClient) processMessages() {
 			log.Println("connection ended")
 			panic("connection ended")
 		case nextValidId:
-			c.handleNextValidId(scanner)
+			c.handleNextValidId(scanner, fields)
 		case managedAccounts:
-			c.handleManagedAccounts(scanner)
+			c.handleManagedAccounts(fields)
 		case errMsg:
 			c.handleErrorMessage(scanner, fields)
+		case orderStatus, openOrder, openOrderEnd, commissionReport, completedOrder, completedOrdersEnd, positionData, positionEnd, receiveFa, marketDepthExchanges:
+			c.dispatch(msgId, fields)
+		case accountValue, portfolioValue, accountUpdateTime, accountDownloadEnd:
+			// account updates are keyed by account, the client has a single subscription
+			c.dispatch(msgId, fields)
 		default:
-			requestId := getRequestId(msgId, fields)
+			requestId, err := getRequestId(c.ServerVersion, msgId, fields)
+			if err != nil {
+				log.Printf("error routing message: %v", err)
+				continue
+			}
 
-			channel := c.getChannel(requestId)
-			if channel == nil {
+			// unsolicited messages, e.g. executions of filled orders
+			if requestId == noRequest {
+				c.dispatch(msgId, fields)
+				continue
+			}
+
+			receiver := c.getChannel(requestId)
+			if receiver == nil {
 				log.Printf("no receiver found for request id %d: %v", requestId, fields)
 				continue
 			}
 
-			channel <- fields
+			receiver.push(fields)
 		}
 	}
 }
 
-func getRequestId(msgId int, fields []string) int {
+func getRequestId(serverVersion int, msgId int, fields []string) (int, error) {
 	text := ""
 
 	switch msgId {
-	case contractData, tickByTick:
+	case contractData, tickByTick, pnl, pnlSingle, replaceFaEnd, tickRequestParameters, smartComponents, historicalDataUpdate,
+		historicalTicks, historicalTicksBidAsk, historicalTicksLast:
 		text = fields[1]
-	case contractDataEnd, realTimeBars:
+	case contractDataEnd, realTimeBars, executionDataEnd, accountSummary, accountSummaryEnd,
+		positionMulti, positionMultiEnd, accountUpdateMulti, accountUpdateMultiEnd:
+		text = fields[2]
+	case tickPrice, tickSize, tickString, tickGeneric, tickEfp, tickSnapshotEnd, marketDataType, marketDepth, marketDepthL2:
 		text = fields[2]
+	case historicalData:
+		if serverVersion < minServerVerSyntRealtimeBars {
+			text = fields[2]
+		} else {
+			text = fields[1]
+		}
+	case tickOptionComputation:
+		if serverVersion < minServerVerPriceBasedVolatility {
+			text = fields[2]
+		} else {
+			text = fields[1]
+		}
+	case executionData:
+		if serverVersion < minServerVerLastLiquidity {
+			text = fields[2]
+		} else {
+			text = fields[1]
+		}
 	default:
-		log.Fatalf("could not determine request id for message ID %d: %v\n", msgId, fields)
+		return 0, fmt.Errorf("could not determine request id for message ID %d: %v", msgId, fields)
 	}
 
 	requestId, err := strconv.Atoi(text)
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("error parsing request id for message ID %d: %w", msgId, err)
 	}
 
-	return requestId
+	return requestId, nil
 }
 
-func (c *IbClient) handleNextValidId(scanner *parser) {
+func (c *IbClient) handleNextValidId(scanner *parser, fields []string) {
 	scanner.readInt() // skip version
-	c.NextValidOrderId = scanner.readInt()
+	orderId := scanner.readInt()
 
-	close(c.ready)
+	c.seedOrderId(orderId)
+	c.dispatch(nextValidId, fields)
 
-	log.Printf("next valid id: %v", c.NextValidOrderId)
+	c.readyOnce.Do(func() {
+		close(c.ready)
+	})
+
+	log.Printf("next valid id: %v", orderId)
 }
 
-func (c *IbClient) handleManagedAccounts(scanner *parser) {
-	scanner.readInt() // skip version
-	c.ManagedAccounts = scanner.readString()
+func (c *IbClient) handleManagedAccounts(fields []string) {
+	accounts := decodeManagedAccounts(fields)
+
+	c.accountsMutex.Lock()
+	c.accounts = accounts
+	c.ManagedAccounts = fields[2]
+	c.accountsMutex.Unlock()
+
+	c.dispatch(managedAccounts, fields)
+
+	log.Printf("managed accounts: %v", accounts)
+}
+
+// Accounts returns the ids of the accounts managed by the connected user, as last reported by TWS.
+func (c *IbClient) Accounts() []string {
+	c.accountsMutex.Lock()
+	defer c.accountsMutex.Unlock()
+
+	accounts := make([]string, len(c.accounts))
+	copy(accounts, c.accounts)
+
+	return accounts
+}
+
+// RefreshManagedAccounts requests the managed accounts from TWS and returns them once received.
+func (c *IbClient) RefreshManagedAccounts(ctx context.Context) ([]string, error) {
+	messages := c.addListener(managedAccounts)
+	defer c.removeListener(messages)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(requestManagedAccounts)
+	message.addInt(version)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return nil, fmt.Errorf("error sending managed accounts request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("managed accounts request cancelled: %w", ctx.Err())
+	case message := <-messages:
+		return decodeManagedAccounts(message), nil
+	}
+}
 
-	log.Printf("managed accounts: %v", c.ManagedAccounts)
+// AccountChanges streams the managed accounts whenever TWS reports a list different from the previous one, e.g. after a reconnect, until the context is cancelled.
+// The accounts are requested again when the stream starts, so that a change missed before is reported.
+func (c *IbClient) AccountChanges(ctx context.Context) (<-chan []string, error) {
+	messages := c.addListener(managedAccounts)
+	previous := c.Accounts()
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(requestManagedAccounts)
+	message.addInt(version)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		c.removeListener(messages)
+		return nil, fmt.Errorf("error sending managed accounts request: %w", err)
+	}
+
+	changes := make(chan []string)
+
+	go func() {
+		defer close(changes)
+		defer c.removeListener(messages)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case message := <-messages:
+				accounts := decodeManagedAccounts(message)
+				if equalAccounts(accounts, previous) {
+					continue
+				}
+				previous = accounts
+
+				select {
+				case changes <- accounts:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func equalAccounts(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (c *IbClient) handleErrorMessage(scanner *parser, fields []string) {
@@ -244,11 +496,19 @@ func (c *IbClient) handleErrorMessage(scanner *parser, fields []string) {
 
 		if requestId == noRequest {
 			log.Printf("error message[%d]: %s", code, msg)
+			// e.g. the rejection of a financial advisor request, which has no request id
+			c.dispatch(errMsg, fields)
+		} else if c.isOrderId(requestId) {
+			// order and request ids share the same range, orders take precedence
+			if !c.dispatch(errMsg, fields) {
+				log.Printf("no receiver found for order id %d:%d: %v", requestId, code, msg)
+			}
 		} else {
-			channel, ok := c.channels[requestId]
-			if ok {
-				channel <- fields
+			receiver := c.getChannel(requestId)
+			if receiver != nil {
+				receiver.push(fields)
 			} else {
+				// e.g. the error of a request already cancelled, order listeners only receive the errors of known orders
 				log.Printf("no receiver found for request id %d:%d: %v", requestId, code, msg)
 			}
 		}
@@ -565,32 +825,2540 @@ func (c *IbClient) ContractDetails(ctx context.Context, contract Contract) ([]Co
 	}
 }
 
-// Utility Methods
+// PlaceOrder places or modifies an order.
+// The orderId must be unique for the session, use the same id to modify an order that is still working.
+// Set Transmit on the order to have TWS send it to the market, otherwise it is only created at TWS.
+// The order is not sent when the context is already cancelled, PlaceOrder does not wait for TWS to acknowledge it.
+func (c *IbClient) PlaceOrder(ctx context.Context, orderId int, contract Contract, order Order) error {
+	if c.ServerVersion < minServerVersionTradingClass {
+		return fmt.Errorf("server version %d does not support TradingClass field in Contract", c.ServerVersion)
+	}
 
-func (c *IbClient) addChannel(requestId int) chan []string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if c.ServerVersion < minServerVerFractionalPositions && order.TotalQuantity != math.Trunc(order.TotalQuantity) {
+		return fmt.Errorf("server version %d does not support fractional order quantities", c.ServerVersion)
+	}
+
+	if c.ServerVersion < minServerVerCashQty && order.CashQuantity != 0 && order.CashQuantity != math.MaxFloat64 {
+		return fmt.Errorf("server version %d does not support CashQuantity field in Order", c.ServerVersion)
+	}
+
+	if c.ServerVersion < minServerVerAlgoOrders && (order.Algo != nil || order.AlgoStrategy != "") {
+		return fmt.Errorf("server version %d does not support algo orders", c.ServerVersion)
+	}
+
+	if order.Algo != nil {
+		if err := order.Algo.Validate(); err != nil {
+			return fmt.Errorf("invalid %s algo parameters: %w", order.Algo.Strategy(), err)
+		}
+	}
+
+	if c.ServerVersion < minServerVerWhatIfOrders && order.WhatIf {
+		return fmt.Errorf("server version %d does not support what-if orders", c.ServerVersion)
+	}
+
+	if c.ServerVersion < minServerVerPeggedToBenchmark && len(order.Conditions) > 0 {
+		return fmt.Errorf("server version %d does not support order conditions", c.ServerVersion)
+	}
+
+	if order.OrderType == "PEG BENCH" {
+		return fmt.Errorf("pegged to benchmark orders are not supported")
+	}
+
+	encoder := placeOrderEncoder{
+		serverVersion: c.ServerVersion,
+		version:       45,
+		orderId:       orderId,
+		contract:      contract,
+		order:         order,
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("place order %d cancelled: %w", orderId, err)
+	}
+
+	c.trackOrderId(orderId)
 
-	channel := make(chan []string)
-	c.channels[requestId] = channel
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		return fmt.Errorf("error sending place order request: %w", err)
+	}
 
-	return channel
+	return nil
 }
 
-func (c *IbClient) removeChannel(requestId int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// WhatIfOrder previews the margin and commission impact of an order without placing it.
+// The returned state holds the account's initial margin, maintenance margin and equity with loan before and after the order, and the commission range.
+// The before and change figures are only reported by servers supporting the extended what-if fields.
+func (c *IbClient) WhatIfOrder(ctx context.Context, contract Contract, order Order) (OrderState, error) {
+	if c.ServerVersion < minServerVerWhatIfOrders {
+		return OrderState{}, fmt.Errorf("server version %d does not support what-if orders", c.ServerVersion)
+	}
 
-	channel := c.channels[requestId]
-	if channel != nil {
-		delete(c.channels, requestId)
-		close(channel)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	orderId := c.NextOrderId()
+	events := c.orderEvents(ctx, orderId)
+
+	order.WhatIf = true
+	order.Transmit = true
+
+	if err := c.PlaceOrder(ctx, orderId, contract, order); err != nil {
+		return OrderState{}, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return OrderState{}, fmt.Errorf("what-if order %d cancelled: %w", orderId, ctx.Err())
+
+		case event := <-events:
+			if event.OpenOrder != nil {
+				return event.OpenOrder.OrderState, nil
+			}
+
+			if event.Error != nil && !isOrderWarning(event.Error.Code) {
+				return OrderState{}, *event.Error
+			}
+		}
 	}
 }
 
-func (c *IbClient) getChannel(requestId int) chan []string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// ModifyOrder modifies an order that is still working by sending it again with the same id.
+// It waits for TWS to report the status of the modified order. Notifications such as 399 leave the order working and are not errors,
+// an order that was cancelled instead is returned as Cancelled with the error.
+func (c *IbClient) ModifyOrder(ctx context.Context, orderId int, contract Contract, order Order) (OrderStatus, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	return c.channels[requestId]
+	events := c.orderEvents(ctx, orderId)
+
+	if err := c.PlaceOrder(ctx, orderId, contract, order); err != nil {
+		return OrderStatus{}, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return OrderStatus{}, fmt.Errorf("modify order %d cancelled: %w", orderId, ctx.Err())
+
+		case event := <-events:
+			if event.Status != nil {
+				return *event.Status, nil
+			}
+
+			if event.Error != nil && !isOrderWarning(event.Error.Code) {
+				if event.Error.Code == orderCancelled {
+					return OrderStatus{OrderId: orderId, Status: "Cancelled"}, *event.Error
+				}
+				return OrderStatus{}, *event.Error
+			}
+		}
+	}
+}
+
+// CancelOrder cancels an order and waits for TWS to confirm it.
+// It returns the last status reported for the order, which is Filled if the order was filled before it could be cancelled.
+// An OrderError is returned when TWS refuses the cancellation, e.g. with code 10147 when the order is not found or 10148 when it can no longer be cancelled.
+// Manual cancel times are not supported, they need a server version above those negotiated by this client.
+func (c *IbClient) CancelOrder(ctx context.Context, orderId int) (OrderStatus, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := c.orderEvents(ctx, orderId)
+
+	encoder := cancelOrderEncoder{
+		serverVersion: c.ServerVersion,
+		version:       1,
+		orderId:       orderId,
+	}
+
+	c.trackOrderId(orderId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		return OrderStatus{}, fmt.Errorf("error sending cancel order request: %w", err)
+	}
+
+	status := OrderStatus{OrderId: orderId}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("cancel order %d cancelled: %w", orderId, ctx.Err())
+
+		case event := <-events:
+			if event.Status != nil {
+				status = *event.Status
+				if isOrderDone(status.Status) {
+					return status, nil
+				}
+			}
+
+			if event.Error != nil {
+				switch {
+				case event.Error.Code == orderCancelled:
+					status.Status = "Cancelled"
+					return status, nil
+				case !isOrderWarning(event.Error.Code):
+					return status, *event.Error
+				}
+			}
+		}
+	}
+}
+
+// GlobalCancel cancels all open orders, including those placed by other clients or directly in TWS.
+// It waits until every order that was open when the request was made is done and returns their final statuses.
+func (c *IbClient) GlobalCancel(ctx context.Context) ([]OrderStatus, error) {
+	if c.ServerVersion < minServerVerReqGlobalCancel {
+		return nil, fmt.Errorf("server version %d does not support global cancel requests", c.ServerVersion)
+	}
+
+	messages := c.addListener(openOrder, openOrderEnd, orderStatus, errMsg)
+	defer c.removeListener(messages)
+
+	// find the open orders
+
+	message := messageBuilder{}
+	message.addInt(requestAllOpenOrders)
+	message.addInt(1) // version
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return nil, fmt.Errorf("error sending request for open orders: %w", err)
+	}
+
+	// orders are keyed by perm id, orders placed in TWS all share order id 0
+	statuses := map[int]OrderStatus{}
+	pending := map[int]bool{}
+	cancelRequested := false
+
+	for !cancelRequested || len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return orderStatuses(statuses), fmt.Errorf("global cancel request cancelled: %w", ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				continue
+			}
+
+			switch messageId {
+			case openOrder:
+				if cancelRequested {
+					continue
+				}
+
+				order := decodeOpenOrder(c.ServerVersion, message)
+				if !isOrderDone(order.OrderState.Status) {
+					pending[order.Order.PermId] = true
+					statuses[order.Order.PermId] = OrderStatus{
+						OrderId:  order.Order.OrderId,
+						Status:   order.OrderState.Status,
+						PermId:   order.Order.PermId,
+						ParentId: order.Order.ParentId,
+						ClientId: order.Order.ClientId,
+					}
+				}
+
+			case openOrderEnd:
+				if cancelRequested {
+					continue
+				}
+
+				message := messageBuilder{}
+				message.addInt(requestGlobalCancel)
+				message.addInt(1) // version
+
+				if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+					return nil, fmt.Errorf("error sending global cancel request: %w", err)
+				}
+
+				cancelRequested = true
+
+			case orderStatus:
+				status := decodeOrderStatus(c.ServerVersion, message)
+				if _, ok := statuses[status.PermId]; !ok {
+					continue
+				}
+
+				statuses[status.PermId] = status
+				if isOrderDone(status.Status) {
+					delete(pending, status.PermId)
+				}
+
+			case errMsg:
+				orderError := decodeOrderError(message)
+				if isOrderWarning(orderError.Code) {
+					continue
+				}
+
+				for permId, status := range statuses {
+					if status.OrderId != orderError.OrderId || status.ClientId != c.clientId {
+						continue
+					}
+
+					if orderError.Code == orderCancelled {
+						status.Status = "Cancelled"
+						statuses[permId] = status
+					}
+					delete(pending, permId)
+				}
+			}
+		}
+	}
+
+	return orderStatuses(statuses), nil
+}
+
+// orderCancelled is the code of the error message confirming an order cancellation.
+const orderCancelled = 202
+
+// isOrderDone reports whether an order status is final.
+func isOrderDone(status string) bool {
+	switch status {
+	case "Cancelled", "ApiCancelled", "Filled", "Inactive":
+		return true
+	default:
+		return false
+	}
+}
+
+// isWarning reports whether a TWS error code is a warning or notification that does not end a request.
+func isWarning(code int) bool {
+	return code >= 2100 && code < 2200
+}
+
+// isOrderWarning reports whether a TWS error code for an order is a notification that leaves the order working,
+// e.g. 399 when the order will only be placed at the open or 404 when it is held while shares are located.
+func isOrderWarning(code int) bool {
+	switch code {
+	case 399, 404:
+		return true
+	default:
+		return isWarning(code)
+	}
+}
+
+// isMarketDataWarning reports whether a TWS error code for a market data request is a notification that does not end the request, e.g. delayed data being sent instead.
+func isMarketDataWarning(code int) bool {
+	switch code {
+	case 10090, 10167, 10197:
+		return true
+	default:
+		return isWarning(code)
+	}
+}
+
+func orderStatuses(statuses map[int]OrderStatus) []OrderStatus {
+	result := make([]OrderStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OrderId < result[j].OrderId
+	})
+
+	return result
+}
+
+// Executions requests the executions matching the filter, with their commission reports.
+// Only the executions of the current day, or of the last seven days when configured in TWS, are returned.
+// The request ends with the last execution, the commission reports TWS sends after it are not joined:
+// fresh fills may be returned without their CommissionReport. Use ExecutionReports to receive them as they arrive.
+func (c *IbClient) Executions(ctx context.Context, filter ExecutionFilter) ([]Execution, error) {
+	if c.ServerVersion < minServerVerExecutionDataChain {
+		return nil, fmt.Errorf("server version %d does not support execution data requests", c.ServerVersion)
+	}
+
+	encoder := executionsEncoder{
+		serverVersion: c.ServerVersion,
+		version:       3,
+		requestId:     c.nextRequestId(),
+		filter:        filter,
+	}
+
+	commissions := c.addListener(commissionReport)
+	defer c.removeListener(commissions)
+
+	messages := c.addChannel(encoder.requestId)
+
+	err := c.MessageBus.WritePacket(encoder.encode())
+	if err != nil {
+		return nil, fmt.Errorf("error sending executions request: %w", err)
+	}
+
+	// process response
+
+	executions := []Execution{}
+	reports := map[string]CommissionReport{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.removeChannel(encoder.requestId)
+			return joinCommissionReports(executions, reports), fmt.Errorf("executions request %d cancelled: %w", encoder.requestId, ctx.Err())
+
+		case message := <-commissions:
+			report := decodeCommissionReport(message)
+			reports[report.ExecId] = report
+
+		case message := <-messages:
+			if message == nil {
+				return joinCommissionReports(executions, reports), nil
+			}
+
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			if messageId == executionDataEnd {
+				c.removeChannel(encoder.requestId)
+			} else if messageId == executionData {
+				execution := decodeExecution(c.ServerVersion, message)
+				executions = append(executions, execution)
+			} else if messageId == errMsg {
+				c.removeChannel(encoder.requestId)
+				return joinCommissionReports(executions, reports), decodeRequestError(message)
+			} else {
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+func joinCommissionReports(executions []Execution, reports map[string]CommissionReport) []Execution {
+	for i, execution := range executions {
+		if report, ok := reports[execution.ExecId]; ok {
+			executions[i].CommissionReport = report
+		}
+	}
+
+	return executions
+}
+
+// ExecutionReports streams the executions of orders as they are filled, until the context is cancelled.
+// An execution is sent as soon as it is reported, without its commission. TWS reports the commission shortly after,
+// it is sent as a separate Execution holding only the ExecId and the CommissionReport.
+// Commission reports may also arrive for executions sent before the stream started, or never for some venues.
+func (c *IbClient) ExecutionReports(ctx context.Context) (<-chan Execution, error) {
+	messages := c.addListener(executionData, commissionReport)
+
+	executions := make(chan Execution)
+
+	go func() {
+		defer close(executions)
+		defer c.removeListener(messages)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case message := <-messages:
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+					continue
+				}
+
+				var execution Execution
+				if messageId == executionData {
+					execution = decodeExecution(c.ServerVersion, message)
+				} else {
+					report := decodeCommissionReport(message)
+					execution = Execution{ExecId: report.ExecId, CommissionReport: report}
+				}
+
+				select {
+				case executions <- execution:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return executions, nil
+}
+
+// CompletedOrders requests the orders that were filled or cancelled during the current day.
+// When apiOnly is set only the orders placed through the API are returned.
+func (c *IbClient) CompletedOrders(ctx context.Context, apiOnly bool) ([]CompletedOrder, error) {
+	if c.ServerVersion < minServerVerCompletedOrders {
+		return nil, fmt.Errorf("server version %d does not support completed orders requests", c.ServerVersion)
+	}
+
+	// responses carry no request id, only one request can be active
+	c.completedOrdersMutex.Lock()
+	defer c.completedOrdersMutex.Unlock()
+
+	messages := c.addListener(completedOrder, completedOrdersEnd)
+	defer c.removeListener(messages)
+
+	message := messageBuilder{}
+	message.addInt(requestCompleteOrders)
+	message.addBool(apiOnly)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return nil, fmt.Errorf("error sending completed orders request: %w", err)
+	}
+
+	// process response
+
+	orders := []CompletedOrder{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return orders, fmt.Errorf("completed orders request cancelled: %w", ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			if messageId == completedOrdersEnd {
+				return orders, nil
+			} else if messageId == completedOrder {
+				order := decodeCompletedOrder(c.ServerVersion, message)
+				orders = append(orders, order)
+			} else {
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// OCA types, tell how to handle the remaining orders of a One-Cancels-All group when one order executes.
+const (
+	OcaCancelWithBlock = 1 // Cancel all remaining orders with block.
+	OcaReduceWithBlock = 2 // Remaining orders are proportionately reduced in size with block.
+	OcaReduceNonBlock  = 3 // Remaining orders are proportionately reduced in size with no block.
+)
+
+// OneCancelsAll returns copies of the orders assigned to the One-Cancels-All group.
+func OneCancelsAll(group string, ocaType int, orders ...Order) []Order {
+	result := make([]Order, len(orders))
+
+	for i, order := range orders {
+		order.OcaGroup = group
+		order.OcaType = ocaType
+		result[i] = order
+	}
+
+	return result
+}
+
+// PlaceBracket places a bracket order: a parent order with attached take profit and stop loss orders.
+// The legs get consecutive order ids, the children are attached to the parent and only the stop loss is transmitted, which sends the whole bracket at once.
+// Children without a quantity get the parent's quantity.
+// The returned group tracks the legs until they are all done or the context is cancelled.
+func (c *IbClient) PlaceBracket(ctx context.Context, contract Contract, parent Order, takeProfit Order, stopLoss Order) (*OrderGroup, error) {
+	if takeProfit.Action == parent.Action || stopLoss.Action == parent.Action {
+		return nil, fmt.Errorf("bracket children must be on the opposite side of the parent order")
+	}
+
+	parentId := c.nextOrderIds(3)
+
+	parent.Transmit = false
+
+	for _, child := range []*Order{&takeProfit, &stopLoss} {
+		child.ParentId = parentId
+		child.Transmit = false
+		if child.TotalQuantity == 0 {
+			child.TotalQuantity = parent.TotalQuantity
+		}
+	}
+
+	stopLoss.Transmit = true
+
+	orders := []Order{parent, takeProfit, stopLoss}
+
+	return c.placeOrderGroup(ctx, parentId, contract, orders)
+}
+
+// PlaceOrderGroup places related orders, e.g. the orders of a One-Cancels-All group, with consecutive order ids.
+// The returned group tracks the orders until they are all done or the context is cancelled.
+func (c *IbClient) PlaceOrderGroup(ctx context.Context, contract Contract, orders ...Order) (*OrderGroup, error) {
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("no orders to place")
+	}
+
+	return c.placeOrderGroup(ctx, c.nextOrderIds(len(orders)), contract, orders)
+}
+
+func (c *IbClient) placeOrderGroup(ctx context.Context, firstOrderId int, contract Contract, orders []Order) (*OrderGroup, error) {
+	orderIds := make([]int, len(orders))
+	for i := range orders {
+		orderIds[i] = firstOrderId + i
+	}
+
+	group, stop := c.trackOrders(ctx, orderIds)
+
+	for i, order := range orders {
+		if err := c.PlaceOrder(ctx, orderIds[i], contract, order); err != nil {
+			stop()
+
+			// don't leave the legs already sent to TWS behind
+			for _, orderId := range orderIds[:i] {
+				encoder := cancelOrderEncoder{serverVersion: c.ServerVersion, version: 1, orderId: orderId}
+				if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+					log.Printf("error cancelling order %d: %v", orderId, err)
+				}
+			}
+
+			return nil, fmt.Errorf("error placing order %d of group: %w", orderIds[i], err)
+		}
+	}
+
+	return group, nil
+}
+
+// OrderGroup tracks the combined status of related orders, e.g. the legs of a bracket order.
+type OrderGroup struct {
+	OrderIds []int // The ids of the orders in the group, in the order they were placed.
+
+	mu       sync.Mutex
+	statuses map[int]OrderStatus
+	errors   map[int]OrderError
+	done     chan struct{}
+}
+
+// Statuses returns the last reported status of each order, in the order of OrderIds.
+// Orders without a reported status have an empty Status.
+func (g *OrderGroup) Statuses() []OrderStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statuses := make([]OrderStatus, len(g.OrderIds))
+	for i, orderId := range g.OrderIds {
+		status, ok := g.statuses[orderId]
+		if !ok {
+			status.OrderId = orderId
+		}
+		statuses[i] = status
+	}
+
+	return statuses
+}
+
+// Errors returns the last error reported for each order that had one, e.g. a rejection.
+func (g *OrderGroup) Errors() []OrderError {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	errors := []OrderError{}
+	for _, orderId := range g.OrderIds {
+		if orderError, ok := g.errors[orderId]; ok {
+			errors = append(errors, orderError)
+		}
+	}
+
+	return errors
+}
+
+// Done is closed when every order of the group is filled, cancelled, inactive or rejected with an error, or when tracking stops because the context was cancelled.
+func (g *OrderGroup) Done() <-chan struct{} {
+	return g.done
+}
+
+// update records an order event and reports whether all the orders are done.
+func (g *OrderGroup) update(event OrderEvent) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if event.Status != nil {
+		g.statuses[event.OrderId] = *event.Status
+	}
+
+	if event.Error != nil && !isOrderWarning(event.Error.Code) {
+		g.errors[event.OrderId] = *event.Error
+	}
+
+	// an order rejected with an error, e.g. 201, may not be followed by an Inactive status
+	for _, orderId := range g.OrderIds {
+		_, failed := g.errors[orderId]
+		if !failed && !isOrderDone(g.statuses[orderId].Status) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trackOrders starts tracking the orders of a group. Tracking ends when all the orders are done or the returned function is called.
+func (c *IbClient) trackOrders(ctx context.Context, orderIds []int) (*OrderGroup, context.CancelFunc) {
+	group := &OrderGroup{
+		OrderIds: orderIds,
+		statuses: map[int]OrderStatus{},
+		errors:   map[int]OrderError{},
+		done:     make(chan struct{}),
+	}
+
+	ids := map[int]bool{}
+	for _, orderId := range orderIds {
+		ids[orderId] = true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events := c.orderEvents(ctx, noRequest)
+
+	go func() {
+		defer close(group.done)
+		defer cancel()
+
+		for event := range events {
+			if ids[event.OrderId] && group.update(event) {
+				return
+			}
+		}
+	}()
+
+	return group, cancel
+}
+
+// OrderEvents streams the status changes, open order descriptions and errors of all orders, until the context is cancelled.
+func (c *IbClient) OrderEvents(ctx context.Context) (<-chan OrderEvent, error) {
+	return c.orderEvents(ctx, noRequest), nil
+}
+
+// OrderUpdates streams the status changes, open order descriptions and errors of a single order, until the context is cancelled.
+func (c *IbClient) OrderUpdates(ctx context.Context, orderId int) (<-chan OrderEvent, error) {
+	return c.orderEvents(ctx, orderId), nil
+}
+
+// orderEvents streams the events of the order with the given id, or of all orders when the id is noRequest.
+func (c *IbClient) orderEvents(ctx context.Context, orderId int) <-chan OrderEvent {
+	messages := c.addListener(orderStatus, openOrder, errMsg)
+
+	events := make(chan OrderEvent)
+
+	go func() {
+		defer close(events)
+		defer c.removeListener(messages)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case message := <-messages:
+				event, ok := c.decodeOrderEvent(message)
+				if !ok || event.OrderId == noRequest || (orderId != noRequest && event.OrderId != orderId) {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events
+}
+
+// decodeOrderEvent converts an order status, open order or error message into an OrderEvent.
+func (c *IbClient) decodeOrderEvent(message []string) (OrderEvent, bool) {
+	messageId, err := strconv.Atoi(message[0])
+	if err != nil {
+		log.Printf("error parsing messageId [%s]: %v", message[0], err)
+		return OrderEvent{}, false
+	}
+
+	switch messageId {
+	case orderStatus:
+		status := decodeOrderStatus(c.ServerVersion, message)
+		return OrderEvent{OrderId: status.OrderId, Status: &status}, true
+	case openOrder:
+		order := decodeOpenOrder(c.ServerVersion, message)
+		return OrderEvent{OrderId: order.Order.OrderId, OpenOrder: &order}, true
+	case errMsg:
+		orderError := decodeOrderError(message)
+		return OrderEvent{OrderId: orderError.OrderId, Error: &orderError}, true
+	default:
+		log.Printf("unexpected message: %v", message)
+		return OrderEvent{}, false
+	}
+}
+
+// AccountSummary requests a snapshot of the summary values of the accounts in a group, keyed by account.
+// The group is "All" for all accounts, or the name of a financial advisor group. Only the requested tags are reported.
+func (c *IbClient) AccountSummary(ctx context.Context, group string, tags []AccountSummaryTag) (map[string][]AccountSummaryValue, error) {
+	requestId, messages, err := c.requestAccountSummary(group, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.cancelAccountSummary(requestId)
+
+	summaries := map[string][]AccountSummaryValue{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return summaries, fmt.Errorf("account summary request %d cancelled: %w", requestId, ctx.Err())
+
+		case message := <-messages:
+			if message == nil {
+				return summaries, nil
+			}
+
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case accountSummary:
+				value := decodeAccountSummary(message)
+				summaries[value.Account] = append(summaries[value.Account], value)
+			case accountSummaryEnd:
+				return summaries, nil
+			case errMsg:
+				return summaries, decodeRequestError(message)
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// AccountSummaryUpdates streams the summary values of the accounts in a group until the context is cancelled.
+// All the requested values are sent first, then each value again whenever it changes.
+func (c *IbClient) AccountSummaryUpdates(ctx context.Context, group string, tags []AccountSummaryTag) (<-chan AccountSummaryValue, error) {
+	requestId, messages, err := c.requestAccountSummary(group, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(chan AccountSummaryValue)
+
+	go func() {
+		defer close(values)
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelAccountSummary(requestId)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				switch messageId {
+				case accountSummary:
+					select {
+					case values <- decodeAccountSummary(message):
+					case <-ctx.Done():
+					}
+				case accountSummaryEnd:
+				case errMsg:
+					log.Printf("account summary request %d failed: %v", requestId, decodeRequestError(message))
+					c.removeChannel(requestId)
+					return
+				default:
+					log.Printf("unexpected message: %v", message)
+				}
+			}
+		}
+	}()
+
+	return values, nil
+}
+
+func (c *IbClient) requestAccountSummary(group string, tags []AccountSummaryTag) (int, chan []string, error) {
+	if c.ServerVersion < minServerVerAccountSummary {
+		return 0, nil, fmt.Errorf("server version %d does not support account summary requests", c.ServerVersion)
+	}
+
+	encoder := accountSummaryEncoder{
+		serverVersion: c.ServerVersion,
+		version:       1,
+		requestId:     c.nextRequestId(),
+		group:         group,
+		tags:          tags,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return 0, nil, fmt.Errorf("error sending account summary request: %w", err)
+	}
+
+	return encoder.requestId, messages, nil
+}
+
+// cancelAccountSummary cancels an account summary subscription.
+func (c *IbClient) cancelAccountSummary(requestId int) {
+	c.removeChannel(requestId)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(cancelAccountSummary)
+	message.addInt(version)
+	message.addInt(requestId)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel account summary %d: %v", requestId, err)
+	}
+}
+
+// Positions requests the positions of all the accessible accounts.
+func (c *IbClient) Positions(ctx context.Context) ([]Position, error) {
+	messages := c.addListener(positionData, positionEnd)
+	defer c.removeListener(messages)
+
+	if err := c.subscribePositions(); err != nil {
+		return nil, err
+	}
+	defer c.unsubscribePositions()
+
+	positions := []Position{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return positions, fmt.Errorf("positions request cancelled: %w", ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			if messageId == positionEnd {
+				return positions, nil
+			} else if messageId == positionData {
+				positions = append(positions, decodePosition(message))
+			} else {
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// PositionUpdates streams the positions of all the accessible accounts until the context is cancelled.
+// All the positions are sent first, then each position again whenever it changes. A closed position is sent with a zero quantity.
+func (c *IbClient) PositionUpdates(ctx context.Context) (<-chan Position, error) {
+	messages := c.addListener(positionData)
+
+	if err := c.subscribePositions(); err != nil {
+		c.removeListener(messages)
+		return nil, err
+	}
+
+	positions := make(chan Position)
+
+	go func() {
+		defer close(positions)
+		defer c.removeListener(messages)
+		defer c.unsubscribePositions()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case message := <-messages:
+				select {
+				case positions <- decodePosition(message):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return positions, nil
+}
+
+// subscribePositions requests the positions. The client has a single position subscription, every request resends all the positions.
+func (c *IbClient) subscribePositions() error {
+	if c.ServerVersion < minServerVerPositions {
+		return fmt.Errorf("server version %d does not support position requests", c.ServerVersion)
+	}
+
+	c.positionsMutex.Lock()
+	defer c.positionsMutex.Unlock()
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(requestPositions)
+	message.addInt(version)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return fmt.Errorf("error sending positions request: %w", err)
+	}
+
+	c.positionsCount++
+
+	return nil
+}
+
+// unsubscribePositions cancels the position subscription once it is no longer used.
+func (c *IbClient) unsubscribePositions() {
+	c.positionsMutex.Lock()
+	defer c.positionsMutex.Unlock()
+
+	c.positionsCount--
+	if c.positionsCount > 0 {
+		return
+	}
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(cancelPositions)
+	message.addInt(version)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel positions: %v", err)
+	}
+}
+
+// PositionsMulti requests the positions of an account or model.
+// An empty account selects all the accounts, an empty model code selects the positions that are not part of a model.
+func (c *IbClient) PositionsMulti(ctx context.Context, account string, modelCode string) ([]Position, error) {
+	requestId, messages, err := c.requestPositionsMulti(account, modelCode)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.cancelMulti(cancelPositionsMulti, requestId)
+
+	positions := []Position{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return positions, fmt.Errorf("positions request %d cancelled: %w", requestId, ctx.Err())
+
+		case message := <-messages:
+			if message == nil {
+				return positions, nil
+			}
+
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case positionMulti:
+				positions = append(positions, decodePositionMulti(message))
+			case positionMultiEnd:
+				return positions, nil
+			case errMsg:
+				return positions, decodeRequestError(message)
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// PositionMultiUpdates streams the positions of an account or model until the context is cancelled.
+// All the positions are sent first, then each position again whenever it changes.
+func (c *IbClient) PositionMultiUpdates(ctx context.Context, account string, modelCode string) (<-chan Position, error) {
+	requestId, messages, err := c.requestPositionsMulti(account, modelCode)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(chan Position)
+
+	go func() {
+		defer close(positions)
+
+		c.streamMulti(ctx, requestId, messages, positionMulti, cancelPositionsMulti, func(message []string) {
+			select {
+			case positions <- decodePositionMulti(message):
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return positions, nil
+}
+
+func (c *IbClient) requestPositionsMulti(account string, modelCode string) (int, chan []string, error) {
+	if c.ServerVersion < minServerVerModelsSupport {
+		return 0, nil, fmt.Errorf("server version %d does not support positions multi requests", c.ServerVersion)
+	}
+
+	encoder := positionsMultiEncoder{
+		serverVersion: c.ServerVersion,
+		version:       1,
+		requestId:     c.nextRequestId(),
+		account:       account,
+		modelCode:     modelCode,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return 0, nil, fmt.Errorf("error sending positions multi request: %w", err)
+	}
+
+	return encoder.requestId, messages, nil
+}
+
+// AccountValuesMulti requests the values of an account or model.
+// An empty account selects all the accounts, an empty model code selects the values that are not part of a model.
+// When ledgerAndNLV is set only the cash balances and net liquidation values are reported.
+func (c *IbClient) AccountValuesMulti(ctx context.Context, account string, modelCode string, ledgerAndNLV bool) ([]AccountValue, error) {
+	requestId, messages, err := c.requestAccountUpdatesMulti(account, modelCode, ledgerAndNLV)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.cancelMulti(cancelAccountUpdatesMulti, requestId)
+
+	values := []AccountValue{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return values, fmt.Errorf("account updates request %d cancelled: %w", requestId, ctx.Err())
+
+		case message := <-messages:
+			if message == nil {
+				return values, nil
+			}
+
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case accountUpdateMulti:
+				values = append(values, decodeAccountUpdateMulti(message))
+			case accountUpdateMultiEnd:
+				return values, nil
+			case errMsg:
+				return values, decodeRequestError(message)
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// AccountValueMultiUpdates streams the values of an account or model until the context is cancelled.
+// All the values are sent first, then each value again whenever it changes.
+func (c *IbClient) AccountValueMultiUpdates(ctx context.Context, account string, modelCode string, ledgerAndNLV bool) (<-chan AccountValue, error) {
+	requestId, messages, err := c.requestAccountUpdatesMulti(account, modelCode, ledgerAndNLV)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(chan AccountValue)
+
+	go func() {
+		defer close(values)
+
+		c.streamMulti(ctx, requestId, messages, accountUpdateMulti, cancelAccountUpdatesMulti, func(message []string) {
+			select {
+			case values <- decodeAccountUpdateMulti(message):
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return values, nil
+}
+
+func (c *IbClient) requestAccountUpdatesMulti(account string, modelCode string, ledgerAndNLV bool) (int, chan []string, error) {
+	if c.ServerVersion < minServerVerModelsSupport {
+		return 0, nil, fmt.Errorf("server version %d does not support account updates multi requests", c.ServerVersion)
+	}
+
+	encoder := accountUpdatesMultiEncoder{
+		serverVersion: c.ServerVersion,
+		version:       1,
+		requestId:     c.nextRequestId(),
+		account:       account,
+		modelCode:     modelCode,
+		ledgerAndNLV:  ledgerAndNLV,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return 0, nil, fmt.Errorf("error sending account updates multi request: %w", err)
+	}
+
+	return encoder.requestId, messages, nil
+}
+
+// streamMulti passes the data messages of a positions or account updates multi subscription to send, until the context is cancelled or the request fails.
+// The subscription is cancelled with the cancel message when the context is done.
+func (c *IbClient) streamMulti(ctx context.Context, requestId int, messages chan []string, dataMessage int, cancelMessage int, send func(message []string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			c.cancelMulti(cancelMessage, requestId)
+			return
+
+		case message := <-messages:
+			if message == nil {
+				return
+			}
+
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case dataMessage:
+				send(message)
+			case positionMultiEnd, accountUpdateMultiEnd:
+			case errMsg:
+				log.Printf("multi request %d failed: %v", requestId, decodeRequestError(message))
+				c.removeChannel(requestId)
+				return
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// cancelMulti cancels a positions or account updates multi subscription.
+func (c *IbClient) cancelMulti(messageId int, requestId int) {
+	c.removeChannel(requestId)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(messageId)
+	message.addInt(version)
+	message.addInt(requestId)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel multi subscription %d: %v", requestId, err)
+	}
+}
+
+// AccountUpdates streams the values and positions of an account until the context is cancelled.
+// All the values and positions are sent first, followed by an update with DownloadEnd set. Changes are then sent every few minutes.
+// The client has a single account updates subscription, only one account can be followed at a time.
+func (c *IbClient) AccountUpdates(ctx context.Context, account string) (<-chan AccountUpdate, error) {
+	messages := c.addListener(accountValue, portfolioValue, accountUpdateTime, accountDownloadEnd)
+
+	if err := c.subscribeAccountUpdates(account); err != nil {
+		c.removeListener(messages)
+		return nil, err
+	}
+
+	updates := make(chan AccountUpdate)
+
+	go func() {
+		defer close(updates)
+		defer c.removeListener(messages)
+		defer c.unsubscribeAccountUpdates()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case message := <-messages:
+				update, ok := decodeAccountUpdate(message)
+				if !ok || !update.belongsTo(account) {
+					continue
+				}
+
+				select {
+				case updates <- update:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// subscribeAccountUpdates requests the updates of an account. Subscribers of the same account share the subscription.
+func (c *IbClient) subscribeAccountUpdates(account string) error {
+	c.accountUpdatesMutex.Lock()
+	defer c.accountUpdatesMutex.Unlock()
+
+	if c.updatesCount > 0 && c.updatesAccount != account {
+		return fmt.Errorf("account updates of %s are already active, only one account can be followed at a time", c.updatesAccount)
+	}
+
+	message := messageBuilder{}
+
+	version := 2
+	message.addInt(requestAccountData)
+	message.addInt(version)
+	message.addBool(true) // subscribe
+	message.addString(account)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return fmt.Errorf("error sending account updates request: %w", err)
+	}
+
+	c.updatesAccount = account
+	c.updatesCount++
+
+	return nil
+}
+
+// unsubscribeAccountUpdates cancels the account updates subscription once it is no longer used.
+func (c *IbClient) unsubscribeAccountUpdates() {
+	c.accountUpdatesMutex.Lock()
+	defer c.accountUpdatesMutex.Unlock()
+
+	c.updatesCount--
+	if c.updatesCount > 0 {
+		return
+	}
+
+	message := messageBuilder{}
+
+	version := 2
+	message.addInt(requestAccountData)
+	message.addInt(version)
+	message.addBool(false) // unsubscribe
+	message.addString(c.updatesAccount)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel account updates of %s: %v", c.updatesAccount, err)
+	}
+}
+
+// PnL streams the daily profit and loss of an account or model until the context is cancelled.
+// An update is sent about every second. An empty model code selects the positions that are not part of a model.
+func (c *IbClient) PnL(ctx context.Context, account string, modelCode string) (<-chan PnL, error) {
+	if c.ServerVersion < minServerVerPnl {
+		return nil, fmt.Errorf("server version %d does not support PnL requests", c.ServerVersion)
+	}
+
+	encoder := pnlEncoder{
+		serverVersion: c.ServerVersion,
+		requestId:     c.nextRequestId(),
+		account:       account,
+		modelCode:     modelCode,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return nil, fmt.Errorf("error sending PnL request: %w", err)
+	}
+
+	updates := make(chan PnL)
+
+	go func() {
+		defer close(updates)
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelPnL(cancelPnl, encoder.requestId)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				switch messageId {
+				case pnl:
+					select {
+					case updates <- decodePnL(c.ServerVersion, message):
+					case <-ctx.Done():
+					}
+				case errMsg:
+					log.Printf("PnL request %d failed: %v", encoder.requestId, decodeRequestError(message))
+					c.removeChannel(encoder.requestId)
+					return
+				default:
+					log.Printf("unexpected message: %v", message)
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// PnLSingle streams the daily profit and loss of a position until the context is cancelled.
+// An update is sent about every second. An empty model code selects the positions that are not part of a model.
+func (c *IbClient) PnLSingle(ctx context.Context, account string, modelCode string, contractId int) (<-chan PnLSingle, error) {
+	if c.ServerVersion < minServerVerPnl {
+		return nil, fmt.Errorf("server version %d does not support PnL requests", c.ServerVersion)
+	}
+
+	encoder := pnlSingleEncoder{
+		serverVersion: c.ServerVersion,
+		requestId:     c.nextRequestId(),
+		account:       account,
+		modelCode:     modelCode,
+		contractId:    contractId,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return nil, fmt.Errorf("error sending single PnL request: %w", err)
+	}
+
+	updates := make(chan PnLSingle)
+
+	go func() {
+		defer close(updates)
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelPnL(cancelPnlSingle, encoder.requestId)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				switch messageId {
+				case pnlSingle:
+					select {
+					case updates <- decodePnLSingle(c.ServerVersion, message):
+					case <-ctx.Done():
+					}
+				case errMsg:
+					log.Printf("single PnL request %d failed: %v", encoder.requestId, decodeRequestError(message))
+					c.removeChannel(encoder.requestId)
+					return
+				default:
+					log.Printf("unexpected message: %v", message)
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// cancelPnL cancels a PnL or single PnL subscription, depending on the cancel message id.
+func (c *IbClient) cancelPnL(messageId int, requestId int) {
+	c.removeChannel(requestId)
+
+	message := messageBuilder{}
+	message.addInt(messageId)
+	message.addInt(requestId)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel PnL %d: %v", requestId, err)
+	}
+}
+
+// Financial advisor data types.
+const (
+	faGroups   = 1
+	faProfiles = 2
+	faAliases  = 3
+)
+
+// FaGroups requests the financial advisor groups. Only available to financial advisor accounts.
+func (c *IbClient) FaGroups(ctx context.Context) ([]FaGroup, error) {
+	data, err := c.requestFa(ctx, faGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFaGroups(data)
+}
+
+// ReplaceFaGroups replaces all the financial advisor groups.
+func (c *IbClient) ReplaceFaGroups(ctx context.Context, groups []FaGroup) error {
+	data, err := encodeFaGroups(groups)
+	if err != nil {
+		return fmt.Errorf("error encoding financial advisor groups: %w", err)
+	}
+
+	return c.replaceFa(ctx, faGroups, data)
+}
+
+// FaProfiles requests the financial advisor allocation profiles. Only available to financial advisor accounts.
+func (c *IbClient) FaProfiles(ctx context.Context) ([]FaProfile, error) {
+	data, err := c.requestFa(ctx, faProfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFaProfiles(data)
+}
+
+// ReplaceFaProfiles replaces all the financial advisor allocation profiles.
+func (c *IbClient) ReplaceFaProfiles(ctx context.Context, profiles []FaProfile) error {
+	data, err := encodeFaProfiles(profiles)
+	if err != nil {
+		return fmt.Errorf("error encoding financial advisor profiles: %w", err)
+	}
+
+	return c.replaceFa(ctx, faProfiles, data)
+}
+
+// FaAliases requests the aliases of the managed accounts. Only available to financial advisor accounts.
+func (c *IbClient) FaAliases(ctx context.Context) ([]FaAlias, error) {
+	data, err := c.requestFa(ctx, faAliases)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFaAliases(data)
+}
+
+// ReplaceFaAliases replaces the aliases of the managed accounts.
+func (c *IbClient) ReplaceFaAliases(ctx context.Context, aliases []FaAlias) error {
+	data, err := encodeFaAliases(aliases)
+	if err != nil {
+		return fmt.Errorf("error encoding account aliases: %w", err)
+	}
+
+	return c.replaceFa(ctx, faAliases, data)
+}
+
+// requestFa requests the XML document of a financial advisor data type.
+func (c *IbClient) requestFa(ctx context.Context, faDataType int) (string, error) {
+	// responses carry no request id, only one request can be active
+	c.faMutex.Lock()
+	defer c.faMutex.Unlock()
+
+	messages := c.addListener(receiveFa, errMsg)
+	defer c.removeListener(messages)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(requestFa)
+	message.addInt(version)
+	message.addInt(faDataType)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return "", fmt.Errorf("error sending financial advisor request: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("financial advisor request cancelled: %w", ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case receiveFa:
+				scanner := &parser{message[2:]}
+				if scanner.readInt() == faDataType {
+					return scanner.readString(), nil
+				}
+			case errMsg:
+				// rejections, e.g. 321 for accounts that are not financial advisors, carry no request id
+				requestError := decodeRequestError(message)
+				if requestError.RequestId == noRequest && !isWarning(requestError.Code) {
+					return "", requestError
+				}
+			}
+		}
+	}
+}
+
+// replaceFa replaces the XML document of a financial advisor data type.
+// Servers supporting it confirm the change, older servers do not and the call returns once the request is sent.
+func (c *IbClient) replaceFa(ctx context.Context, faDataType int, data string) error {
+	encoder := replaceFaEncoder{
+		serverVersion: c.ServerVersion,
+		version:       1,
+		requestId:     c.nextRequestId(),
+		faDataType:    faDataType,
+		data:          data,
+	}
+
+	if c.ServerVersion < minServerVerReplaceFaEnd {
+		if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+			return fmt.Errorf("error sending replace financial advisor request: %w", err)
+		}
+		return nil
+	}
+
+	messages := c.addChannel(encoder.requestId)
+	defer c.removeChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		return fmt.Errorf("error sending replace financial advisor request: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("replace financial advisor request %d cancelled: %w", encoder.requestId, ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case replaceFaEnd:
+				log.Printf("replace financial advisor request %d: %s", encoder.requestId, message[2])
+				return nil
+			case errMsg:
+				return decodeRequestError(message)
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// MarketData requests Level 1 market data: the top of book quotes, last trade, day statistics and the generic ticks asked for.
+// The ticks are streamed until the context is cancelled. Snapshot requests end once all the available ticks have been sent.
+// A MarketDataTypeTick tells whether the ticks that follow are live or delayed, see SetMarketDataType.
+// A RequestParametersTick holds the BBO exchange resolving the exchange codes of the ticks, see ExchangeNames.
+// A request failing, e.g. for an unknown contract, sends an ErrorTick and ends the stream.
+//
+// Parameters:
+// 	contract 			- the Contract for which the data is being requested
+// 	genericTicks 		- ids of additional ticks, e.g. 236 for shortable, see https://interactivebrokers.github.io/tws-api/tick_types.html
+// 	snapshot 			- request a one time snapshot instead of a subscription
+// 	regulatorySnapshot 	- request a regulatory snapshot for US stocks, subject to a fee
+func (c *IbClient) MarketData(ctx context.Context, contract Contract, genericTicks []int, snapshot bool, regulatorySnapshot bool) (<-chan Tick, error) {
+	if c.ServerVersion < minServerVersionTradingClass {
+		return nil, fmt.Errorf("server version %d does not support TradingClass or ContractId fields", c.ServerVersion)
+	}
+
+	if c.ServerVersion < minServerVerReqSmartComponents && regulatorySnapshot {
+		return nil, fmt.Errorf("server version %d does not support regulatory snapshots", c.ServerVersion)
+	}
+
+	encoder := marketDataEncoder{
+		serverVersion:      c.ServerVersion,
+		version:            11,
+		requestId:          c.nextRequestId(),
+		contract:           contract,
+		genericTicks:       genericTicks,
+		snapshot:           snapshot,
+		regulatorySnapshot: regulatorySnapshot,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return nil, fmt.Errorf("error sending market data request: %w", err)
+	}
+
+	ticks := make(chan Tick)
+
+	go func() {
+		defer close(ticks)
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelMarketData(encoder.requestId)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				var tick Tick
+				switch messageId {
+				case tickPrice:
+					tick = decodeTickPrice(c.ServerVersion, message)
+				case tickSize:
+					tick = decodeTickSize(message)
+				case tickString:
+					tick = decodeTickString(message)
+				case tickGeneric:
+					tick = decodeTickGeneric(message)
+				case tickEfp:
+					tick = decodeTickEfp(message)
+				case tickOptionComputation:
+					tick = decodeTickOptionComputation(c.ServerVersion, message)
+				case marketDataType:
+					tick = decodeMarketDataType(message)
+				case tickRequestParameters:
+					tick = decodeTickRequestParameters(message)
+				case tickSnapshotEnd:
+					c.removeChannel(encoder.requestId)
+					continue
+				case errMsg:
+					requestError := decodeRequestError(message)
+					if isMarketDataWarning(requestError.Code) {
+						log.Printf("market data request %d: %v", encoder.requestId, requestError)
+						continue
+					}
+					c.removeChannel(encoder.requestId)
+					tick = ErrorTick{Error: requestError}
+				default:
+					log.Printf("unexpected message: %v", message)
+					continue
+				}
+
+				select {
+				case ticks <- tick:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return ticks, nil
+}
+
+// cancelMarketData cancels a market data subscription.
+func (c *IbClient) cancelMarketData(requestId int) {
+	c.removeChannel(requestId)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(cancelMarketData)
+	message.addInt(version)
+	message.addInt(requestId)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel market data %d: %v", requestId, err)
+	}
+}
+
+// SetMarketDataType switches the type of the market data sent by the following MarketData requests.
+// Live data is sent when available; with MarketDataDelayed, delayed data is sent for the contracts without a market data subscription.
+// The frozen types send the last data recorded at the close when the market is closed.
+func (c *IbClient) SetMarketDataType(ctx context.Context, dataType MarketDataType) error {
+	if c.ServerVersion < minServerVerReqMarketDataType {
+		return fmt.Errorf("server version %d does not support market data type requests", c.ServerVersion)
+	}
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(requestMarketDataType)
+	message.addInt(version)
+	message.addInt(int(dataType))
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return fmt.Errorf("error sending market data type request: %w", err)
+	}
+
+	return nil
+}
+
+// Quote requests a snapshot of the Level 1 market data of a contract.
+// A RequestError is returned when TWS rejects the request, e.g. for an unknown contract or without market data permissions.
+func (c *IbClient) Quote(ctx context.Context, contract Contract) (Quote, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ticks, err := c.MarketData(ctx, contract, nil, true, false)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	quote := Quote{}
+	for tick := range ticks {
+		if tick, ok := tick.(ErrorTick); ok {
+			return quote, tick.Error
+		}
+		quote.Update(tick)
+	}
+
+	if ctx.Err() != nil {
+		return quote, fmt.Errorf("quote request cancelled: %w", ctx.Err())
+	}
+
+	return quote, nil
+}
+
+// QuoteUpdates streams the Level 1 market data of a contract folded into a Quote, sending the full quote on each change until the context is cancelled.
+// The shortable indicator is requested for stocks.
+func (c *IbClient) QuoteUpdates(ctx context.Context, contract Contract) (<-chan Quote, error) {
+	genericTicks := []int{}
+	if contract.SecurityType == "STK" {
+		genericTicks = append(genericTicks, 236) // shortable
+	}
+
+	ticks, err := c.MarketData(ctx, contract, genericTicks, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make(chan Quote)
+
+	go func() {
+		defer close(quotes)
+
+		quote := Quote{}
+		for tick := range ticks {
+			if tick, ok := tick.(ErrorTick); ok {
+				log.Printf("quote updates: %v", tick.Error)
+				continue
+			}
+
+			if !quote.Update(tick) {
+				continue
+			}
+
+			select {
+			case quotes <- quote:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return quotes, nil
+}
+
+// MarketDepth requests the market depth of a contract and streams the changes of its order book until the context is cancelled.
+// TWS may reset the book, which is sent as an event with the DepthReset operation. Use OrderBookUpdates for consistent snapshots of the book.
+// A request failing, e.g. without market depth permissions, sends an event with the DepthError operation and ends the stream.
+//
+// Parameters:
+// 	contract 	- the Contract for which the depth is being requested
+// 	rows 		- the number of rows on each side of the order book
+// 	smartDepth 	- aggregate the depth of all the exchanges of the contract
+func (c *IbClient) MarketDepth(ctx context.Context, contract Contract, rows int, smartDepth bool) (<-chan DepthEvent, error) {
+	if c.ServerVersion < minServerVersionTradingClass {
+		return nil, fmt.Errorf("server version %d does not support TradingClass or ContractId fields", c.ServerVersion)
+	}
+
+	if c.ServerVersion < minServerVerSmartDepth && smartDepth {
+		return nil, fmt.Errorf("server version %d does not support smart depth requests", c.ServerVersion)
+	}
+
+	if rows <= 0 {
+		return nil, fmt.Errorf("invalid number of rows %d", rows)
+	}
+
+	encoder := marketDepthEncoder{
+		serverVersion: c.ServerVersion,
+		version:       5,
+		requestId:     c.nextRequestId(),
+		contract:      contract,
+		rows:          rows,
+		smartDepth:    smartDepth,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		c.removeChannel(encoder.requestId)
+		return nil, fmt.Errorf("error sending market depth request: %w", err)
+	}
+
+	events := make(chan DepthEvent)
+
+	go func() {
+		defer close(events)
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelMarketDepth(encoder.requestId, smartDepth)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				var event DepthEvent
+				switch messageId {
+				case marketDepth:
+					event = decodeMarketDepth(message)
+				case marketDepthL2:
+					event = decodeMarketDepthL2(c.ServerVersion, message)
+				case errMsg:
+					requestError := decodeRequestError(message)
+					if requestError.Code == depthReset {
+						event = DepthEvent{Operation: DepthReset}
+						break
+					}
+					if isWarning(requestError.Code) {
+						log.Printf("market depth request %d: %v", encoder.requestId, requestError)
+						continue
+					}
+					c.removeChannel(encoder.requestId)
+					event = DepthEvent{Operation: DepthError, Error: &requestError}
+				default:
+					log.Printf("unexpected message: %v", message)
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// depthReset is the code of the error message telling the order book was reset and must be cleared.
+const depthReset = 317
+
+// OrderBookUpdates requests the market depth of a contract and sends a snapshot of its order book after each change, until the context is cancelled.
+func (c *IbClient) OrderBookUpdates(ctx context.Context, contract Contract, rows int, smartDepth bool) (<-chan OrderBook, error) {
+	events, err := c.MarketDepth(ctx, contract, rows, smartDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	books := make(chan OrderBook)
+
+	go func() {
+		defer close(books)
+
+		book := OrderBook{}
+		for event := range events {
+			if event.Operation == DepthError {
+				log.Printf("order book updates: %v", event.Error)
+				continue
+			}
+
+			book.Apply(event)
+
+			select {
+			case books <- book.Copy():
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return books, nil
+}
+
+// cancelMarketDepth cancels a market depth subscription.
+func (c *IbClient) cancelMarketDepth(requestId int, smartDepth bool) {
+	c.removeChannel(requestId)
+
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(cancelMarketDepth)
+	message.addInt(version)
+	message.addInt(requestId)
+
+	if c.ServerVersion >= minServerVerSmartDepth {
+		message.addBool(smartDepth)
+	}
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel market depth %d: %v", requestId, err)
+	}
+}
+
+// MarketDepthExchanges requests the exchanges offering market depth.
+func (c *IbClient) MarketDepthExchanges(ctx context.Context) ([]DepthExchange, error) {
+	if c.ServerVersion < minServerVerReqMktDepthExchanges {
+		return nil, fmt.Errorf("server version %d does not support market depth exchanges requests", c.ServerVersion)
+	}
+
+	// responses carry no request id, only one request can be active
+	c.depthExchangesMutex.Lock()
+	defer c.depthExchangesMutex.Unlock()
+
+	messages := c.addListener(marketDepthExchanges)
+	defer c.removeListener(messages)
+
+	message := messageBuilder{}
+	message.addInt(requestMarketDepthExchanges)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return nil, fmt.Errorf("error sending market depth exchanges request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("market depth exchanges request cancelled: %w", ctx.Err())
+	case message := <-messages:
+		return decodeMarketDepthExchanges(c.ServerVersion, message), nil
+	}
+}
+
+// SmartComponents requests the exchanges SMART routes to for a BBO exchange, with the codes identifying them in market data.
+// The BBO exchange of a contract is reported by the RequestParametersTick of its market data.
+func (c *IbClient) SmartComponents(ctx context.Context, bboExchange string) ([]SmartComponent, error) {
+	if c.ServerVersion < minServerVerReqSmartComponents {
+		return nil, fmt.Errorf("server version %d does not support smart components requests", c.ServerVersion)
+	}
+
+	requestId := c.nextRequestId()
+
+	messages := c.addChannel(requestId)
+	defer c.removeChannel(requestId)
+
+	message := messageBuilder{}
+	message.addInt(requestSmartComponents)
+	message.addInt(requestId)
+	message.addString(bboExchange)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		return nil, fmt.Errorf("error sending smart components request: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("smart components request %d cancelled: %w", requestId, ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case smartComponents:
+				components := decodeSmartComponents(message)
+
+				c.componentsMutex.Lock()
+				if c.components == nil {
+					c.components = make(map[string][]SmartComponent)
+				}
+				c.components[bboExchange] = components
+				c.componentsMutex.Unlock()
+
+				return components, nil
+			case errMsg:
+				return nil, decodeRequestError(message)
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// ExchangeNames resolves the exchange codes of a market data tick, e.g. the bid exchange "PQ", into exchange names.
+// The SMART components of the BBO exchange are requested once, then cached. Unknown codes are returned as is.
+func (c *IbClient) ExchangeNames(ctx context.Context, bboExchange string, codes string) ([]string, error) {
+	c.componentsMutex.Lock()
+	components, ok := c.components[bboExchange]
+	c.componentsMutex.Unlock()
+
+	if !ok {
+		var err error
+		if components, err = c.SmartComponents(ctx, bboExchange); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(codes))
+	for _, code := range codes {
+		names = append(names, exchangeName(components, string(code)))
+	}
+
+	return names, nil
+}
+
+func exchangeName(components []SmartComponent, code string) string {
+	for _, component := range components {
+		if component.ExchangeLetter == code {
+			return component.Exchange
+		}
+	}
+	return code
+}
+
+// HistoricalData requests historical bars of a contract, ending at a point in time.
+// Bars with a time are returned in the local time zone, daily and longer bars are dated at midnight of the local time zone.
+//
+// Parameters:
+// 	contract 	- the Contract for which the bars are being requested
+// 	endTime 	- the end of the requested period, the zero time for the present
+// 	duration 	- the length of the requested period, e.g. Days(5)
+// 	barSize 	- the time span of each bar, e.g. BarSize1Min
+// 	whatToShow 	- the kind of data, e.g. WhatToShowTrades
+// 	useRth 		- use regular trading hours
+func (c *IbClient) HistoricalData(ctx context.Context, contract Contract, endTime time.Time, duration Duration, barSize BarSize, whatToShow WhatToShow, useRth bool) ([]Bar, error) {
+	encoder := historicalDataEncoder{
+		serverVersion: c.ServerVersion,
+		version:       6,
+		requestId:     c.nextRequestId(),
+		contract:      contract,
+		endTime:       formatHistoricalTime(endTime),
+		duration:      duration,
+		barSize:       barSize,
+		whatToShow:    whatToShow,
+		useRth:        useRth,
+		formatDate:    2, // seconds since the epoch
+	}
+
+	defer c.removeChannel(encoder.requestId)
+
+	_, bars, err := c.requestHistoricalData(ctx, encoder)
+
+	return bars, err
+}
+
+// HistoricalDataUpdates requests historical bars of a contract up to the present, then keeps the last bar up to date.
+// It returns the history and a stream of updates until the context is cancelled. An update either revises the last bar or starts a new one.
+// Bars of 5 seconds or longer can be kept up to date. A subscription failing later sends an update with the error and ends the stream.
+func (c *IbClient) HistoricalDataUpdates(ctx context.Context, contract Contract, duration Duration, barSize BarSize, whatToShow WhatToShow, useRth bool) ([]Bar, <-chan BarUpdate, error) {
+	if c.ServerVersion < minServerVerSyntRealtimeBars {
+		return nil, nil, fmt.Errorf("server version %d does not support keeping historical data up to date", c.ServerVersion)
+	}
+
+	if barSize == BarSize1Sec {
+		return nil, nil, fmt.Errorf("%s bars cannot be kept up to date", string(barSize))
+	}
+
+	encoder := historicalDataEncoder{
+		serverVersion: c.ServerVersion,
+		version:       6,
+		requestId:     c.nextRequestId(),
+		contract:      contract,
+		duration:      duration,
+		barSize:       barSize,
+		whatToShow:    whatToShow,
+		useRth:        useRth,
+		formatDate:    2, // seconds since the epoch
+		keepUpToDate:  true,
+	}
+
+	messages, bars, err := c.requestHistoricalData(ctx, encoder)
+	if err != nil {
+		c.removeChannel(encoder.requestId)
+		return nil, nil, err
+	}
+
+	updates := make(chan BarUpdate)
+
+	go func() {
+		defer close(updates)
+
+		var last time.Time
+		if len(bars) > 0 {
+			last = bars[len(bars)-1].Time
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.cancelHistoricalData(encoder.requestId)
+				c.removeChannel(encoder.requestId)
+				return
+
+			case message := <-messages:
+				if message == nil {
+					return
+				}
+
+				messageId, err := strconv.Atoi(message[0])
+				if err != nil {
+					log.Printf("error parsing messageId [%s]: %v", message[0], err)
+				}
+
+				switch messageId {
+				case historicalDataUpdate:
+					bar, err := decodeHistoricalDataUpdate(message)
+					if err != nil {
+						log.Printf("error decoding historical data update: %v", err)
+						continue
+					}
+
+					update := BarUpdate{Bar: bar, NewBar: !bar.Time.Equal(last)}
+					last = bar.Time
+
+					select {
+					case updates <- update:
+					case <-ctx.Done():
+					}
+				case errMsg:
+					requestError := decodeRequestError(message)
+					if isWarning(requestError.Code) {
+						log.Printf("historical data request %d: %v", encoder.requestId, requestError)
+						continue
+					}
+					c.removeChannel(encoder.requestId)
+
+					select {
+					case updates <- BarUpdate{Error: &requestError}:
+					case <-ctx.Done():
+					}
+				default:
+					log.Printf("unexpected message: %v", message)
+				}
+			}
+		}
+	}()
+
+	return bars, updates, nil
+}
+
+// requestHistoricalData sends a historical data request and waits for the bars. The channel of the request is left open for the updates of the last bar.
+func (c *IbClient) requestHistoricalData(ctx context.Context, encoder historicalDataEncoder) (chan []string, []Bar, error) {
+	if c.ServerVersion < minServerVersionTradingClass {
+		return nil, nil, fmt.Errorf("server version %d does not support TradingClass or ContractId fields", c.ServerVersion)
+	}
+
+	if err := encoder.barSize.Validate(encoder.duration); err != nil {
+		return nil, nil, err
+	}
+
+	messages := c.addChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		return nil, nil, fmt.Errorf("error sending historical data request: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.cancelHistoricalData(encoder.requestId)
+			return nil, nil, fmt.Errorf("historical data request %d cancelled: %w", encoder.requestId, ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			switch messageId {
+			case historicalData:
+				bars, err := decodeHistoricalData(c.ServerVersion, message)
+				return messages, bars, err
+			case errMsg:
+				requestError := decodeRequestError(message)
+				if isWarning(requestError.Code) {
+					log.Printf("historical data request %d: %v", encoder.requestId, requestError)
+					continue
+				}
+				return nil, nil, requestError
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+		}
+	}
+}
+
+// HistoricalTicks requests historical trades, bid/ask or midpoint ticks of a contract.
+// Exactly one of start and end is set, the other is the zero time. At most 1000 ticks are returned per request,
+// a full day can be paged backwards by ending each request at the time of the earliest tick of the previous one.
+//
+// Parameters:
+// 	contract 	- the Contract for which the ticks are being requested
+// 	start 		- the start of the requested period
+// 	end 		- the end of the requested period
+// 	count 		- the number of distinct data points, at most 1000
+// 	whatToShow 	- WhatToShowTrades, WhatToShowBidAsk or WhatToShowMidpoint
+// 	useRth 		- use regular trading hours
+// 	ignoreSize 	- omit bid/ask ticks which only change the size
+func (c *IbClient) HistoricalTicks(ctx context.Context, contract Contract, start, end time.Time, count int, whatToShow WhatToShow, useRth, ignoreSize bool) (HistoricalTickData, error) {
+	data := HistoricalTickData{}
+
+	if c.ServerVersion < minServerVerHistoricalTicks {
+		return data, fmt.Errorf("server version %d does not support historical ticks requests", c.ServerVersion)
+	}
+
+	if start.IsZero() == end.IsZero() {
+		return data, fmt.Errorf("exactly one of start and end must be set")
+	}
+
+	switch whatToShow {
+	case WhatToShowTrades, WhatToShowBidAsk, WhatToShowMidpoint:
+	default:
+		return data, fmt.Errorf("historical ticks cannot show %s", string(whatToShow))
+	}
+
+	encoder := historicalTicksEncoder{
+		serverVersion: c.ServerVersion,
+		requestId:     c.nextRequestId(),
+		contract:      contract,
+		startTime:     formatHistoricalTime(start),
+		endTime:       formatHistoricalTime(end),
+		numberOfTicks: count,
+		whatToShow:    whatToShow,
+		useRth:        useRth,
+		ignoreSize:    ignoreSize,
+	}
+
+	messages := c.addChannel(encoder.requestId)
+	defer c.removeChannel(encoder.requestId)
+
+	if err := c.MessageBus.WritePacket(encoder.encode()); err != nil {
+		return data, fmt.Errorf("error sending historical ticks request: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return data, fmt.Errorf("historical ticks request %d cancelled: %w", encoder.requestId, ctx.Err())
+
+		case message := <-messages:
+			messageId, err := strconv.Atoi(message[0])
+			if err != nil {
+				log.Printf("error parsing messageId [%s]: %v", message[0], err)
+			}
+
+			done := false
+
+			switch messageId {
+			case historicalTicks:
+				var midpoints []Midpoint
+				midpoints, done = decodeHistoricalTicks(message)
+				data.Midpoints = append(data.Midpoints, midpoints...)
+			case historicalTicksBidAsk:
+				var spreads []BidAsk
+				spreads, done = decodeHistoricalTicksBidAsk(message)
+				data.BidAsks = append(data.BidAsks, spreads...)
+			case historicalTicksLast:
+				var trades []Trade
+				trades, done = decodeHistoricalTicksLast(message)
+				data.Trades = append(data.Trades, trades...)
+			case errMsg:
+				requestError := decodeRequestError(message)
+				if isWarning(requestError.Code) {
+					log.Printf("historical ticks request %d: %v", encoder.requestId, requestError)
+					continue
+				}
+				return data, requestError
+			default:
+				log.Printf("unexpected message: %v", message)
+			}
+
+			if done {
+				return data, nil
+			}
+		}
+	}
+}
+
+// formatHistoricalTime formats the start or end of a historical data request in GMT, the zero time is sent empty.
+func formatHistoricalTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.UTC().Format("20060102 15:04:05") + " GMT"
+}
+
+// cancelHistoricalData cancels a historical data request.
+func (c *IbClient) cancelHistoricalData(requestId int) {
+	message := messageBuilder{}
+
+	version := 1
+	message.addInt(cancelHistoricalData)
+	message.addInt(version)
+	message.addInt(requestId)
+
+	if err := c.MessageBus.WritePacket(message.Encode()); err != nil {
+		log.Printf("error sending request to cancel historical data %d: %v", requestId, err)
+	}
+}
+
+// Utility Methods
+
+// addChannel registers a channel receiving the messages of a request.
+// Messages are queued per request, so a request that stops reading does not hold up the others.
+func (c *IbClient) addChannel(requestId int) chan []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.channels == nil {
+		c.channels = make(map[int]*listener)
+	}
+
+	receiver := newListener()
+	c.channels[requestId] = receiver
+
+	return receiver.messages
+}
+
+// removeChannel unregisters the channel of a request. The channel is closed, discarding messages still queued.
+func (c *IbClient) removeChannel(requestId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	receiver := c.channels[requestId]
+	if receiver != nil {
+		delete(c.channels, requestId)
+		receiver.stop()
+	}
+}
+
+func (c *IbClient) getChannel(requestId int) *listener {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.channels[requestId]
+}
+
+// addListener registers a channel receiving the messages with the given ids that are not tied to a request.
+func (c *IbClient) addListener(messageIds ...int) chan []string {
+	c.listenersMutex.Lock()
+	defer c.listenersMutex.Unlock()
+
+	if c.listeners == nil {
+		c.listeners = make(map[int][]*listener)
+	}
+
+	receiver := newListener()
+	for _, messageId := range messageIds {
+		c.listeners[messageId] = append(c.listeners[messageId], receiver)
+	}
+
+	return receiver.messages
+}
+
+// removeListener unregisters a listener channel. The channel is closed, discarding messages still queued.
+func (c *IbClient) removeListener(channel chan []string) {
+	c.listenersMutex.Lock()
+	defer c.listenersMutex.Unlock()
+
+	for messageId, receivers := range c.listeners {
+		remaining := receivers[:0]
+		for _, receiver := range receivers {
+			if receiver.messages == channel {
+				receiver.stop()
+			} else {
+				remaining = append(remaining, receiver)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(c.listeners, messageId)
+		} else {
+			c.listeners[messageId] = remaining
+		}
+	}
+}
+
+// dispatch delivers a message to the listeners of its message id. It reports whether any listener received it.
+// Messages are queued per listener, so a listener that stops reading does not hold up the others.
+func (c *IbClient) dispatch(messageId int, fields []string) bool {
+	c.listenersMutex.Lock()
+	receivers := append([]*listener{}, c.listeners[messageId]...)
+	c.listenersMutex.Unlock()
+
+	for _, receiver := range receivers {
+		receiver.push(fields)
+	}
+
+	return len(receivers) > 0
+}
+
+// listener forwards dispatched messages to its channel in order, queueing them until they are read.
+type listener struct {
+	messages chan []string // receives the messages, closed when the listener is stopped
+
+	mu       sync.Mutex
+	queue    [][]string
+	queued   chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newListener() *listener {
+	receiver := &listener{
+		messages: make(chan []string),
+		queued:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	go receiver.forward()
+
+	return receiver
+}
+
+// push queues a message without waiting for the reader.
+func (l *listener) push(fields []string) {
+	l.mu.Lock()
+	l.queue = append(l.queue, fields)
+	l.mu.Unlock()
+
+	select {
+	case l.queued <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends forwarding and closes the channel.
+func (l *listener) stop() {
+	l.stopOnce.Do(func() {
+		close(l.done)
+	})
+}
+
+func (l *listener) forward() {
+	defer close(l.messages)
+
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.mu.Unlock()
+
+			select {
+			case <-l.queued:
+				continue
+			case <-l.done:
+				return
+			}
+		}
+
+		fields := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+
+		select {
+		case l.messages <- fields:
+		case <-l.done:
+			return
+		}
+	}
 }
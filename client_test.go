@@ -1 +1,411 @@
 package ibapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextOrderId(t *testing.T) {
+	client := IbClient{}
+
+	client.seedOrderId(10)
+	assert.Equal(t, 10, client.NextOrderId())
+	assert.Equal(t, 11, client.NextOrderId())
+
+	t.Run("ignores seeds behind the sequence", func(t *testing.T) {
+		client.seedOrderId(5)
+		assert.Equal(t, 12, client.NextOrderId())
+	})
+
+	t.Run("moves forward to newer seeds", func(t *testing.T) {
+		client.seedOrderId(20)
+		assert.Equal(t, 20, client.NextOrderId())
+	})
+
+	t.Run("hands out unique ids across goroutines", func(t *testing.T) {
+		ids := make(chan int, 100)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ids <- client.NextOrderId()
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := map[int]bool{}
+		for id := range ids {
+			assert.False(t, seen[id], "duplicate order id %d", id)
+			seen[id] = true
+		}
+		assert.Len(t, seen, 100)
+	})
+}
+
+func TestOneCancelsAll(t *testing.T) {
+	orders := OneCancelsAll("exit", OcaReduceWithBlock,
+		Order{Action: "SELL", OrderType: "LMT", LimitPrice: 4200},
+		Order{Action: "SELL", OrderType: "STP", AuxPrice: 4000},
+	)
+
+	assert.Len(t, orders, 2)
+	for _, order := range orders {
+		assert.Equal(t, "exit", order.OcaGroup)
+		assert.Equal(t, OcaReduceWithBlock, order.OcaType)
+	}
+	assert.Equal(t, "LMT", orders[0].OrderType)
+	assert.Equal(t, "STP", orders[1].OrderType)
+}
+
+func TestOrderGroup(t *testing.T) {
+	group := OrderGroup{
+		OrderIds: []int{10, 11, 12},
+		statuses: map[int]OrderStatus{},
+		errors:   map[int]OrderError{},
+	}
+
+	assert.False(t, group.update(OrderEvent{OrderId: 10, Status: &OrderStatus{OrderId: 10, Status: "Filled"}}))
+	assert.False(t, group.update(OrderEvent{OrderId: 11, Status: &OrderStatus{OrderId: 11, Status: "Submitted"}}))
+	assert.False(t, group.update(OrderEvent{OrderId: 11, Error: &OrderError{OrderId: 11, Code: 2109, Message: "outside regular trading hours"}}))
+
+	statuses := group.Statuses()
+	assert.Equal(t, "Filled", statuses[0].Status)
+	assert.Equal(t, "Submitted", statuses[1].Status)
+	assert.Equal(t, 12, statuses[2].OrderId)
+	assert.Equal(t, "", statuses[2].Status)
+	assert.Empty(t, group.Errors())
+
+	assert.False(t, group.update(OrderEvent{OrderId: 11, Status: &OrderStatus{OrderId: 11, Status: "Filled"}}))
+	assert.True(t, group.update(OrderEvent{OrderId: 12, Status: &OrderStatus{OrderId: 12, Status: "Cancelled"}}))
+
+	t.Run("is done when an order is rejected without a status", func(t *testing.T) {
+		group := OrderGroup{
+			OrderIds: []int{10, 11},
+			statuses: map[int]OrderStatus{},
+			errors:   map[int]OrderError{},
+		}
+
+		assert.False(t, group.update(OrderEvent{OrderId: 10, Status: &OrderStatus{OrderId: 10, Status: "Filled"}}))
+		assert.True(t, group.update(OrderEvent{OrderId: 11, Error: &OrderError{OrderId: 11, Code: 201, Message: "Order rejected"}}))
+		assert.Len(t, group.Errors(), 1)
+	})
+}
+
+func TestAccountChanges(t *testing.T) {
+	client := IbClient{MessageBus: &fakeBus{}, listeners: make(map[int][]*listener)}
+
+	client.handleManagedAccounts([]string{"15", "1", "DU1234,DU5678,"})
+	assert.Equal(t, []string{"DU1234", "DU5678"}, client.Accounts())
+	assert.Equal(t, "DU1234,DU5678,", client.ManagedAccounts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := client.AccountChanges(ctx)
+	assert.Nil(t, err)
+
+	go func() {
+		client.handleManagedAccounts([]string{"15", "1", "DU1234,DU5678,"})
+		client.handleManagedAccounts([]string{"15", "1", "DU1234,"})
+	}()
+
+	assert.Equal(t, []string{"DU1234"}, <-changes)
+	assert.Equal(t, []string{"DU1234"}, client.Accounts())
+}
+
+func TestExchangeNames(t *testing.T) {
+	client := IbClient{
+		components: map[string][]SmartComponent{
+			"9c0001": {{BitNumber: 1, Exchange: "ARCA", ExchangeLetter: "P"}, {BitNumber: 2, Exchange: "NASDAQ", ExchangeLetter: "Q"}},
+		},
+	}
+
+	names, err := client.ExchangeNames(context.Background(), "9c0001", "PQZ")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ARCA", "NASDAQ", "Z"}, names)
+}
+
+func TestDispatch(t *testing.T) {
+	client := IbClient{}
+
+	stalled := client.addListener(orderStatus)
+	messages := client.addListener(orderStatus, openOrder)
+
+	assert.True(t, client.dispatch(orderStatus, []string{"3", "1"}))
+	assert.True(t, client.dispatch(openOrder, []string{"5", "2"}))
+	assert.False(t, client.dispatch(positionData, []string{"61"}))
+
+	assert.Equal(t, []string{"3", "1"}, <-messages)
+	assert.Equal(t, []string{"5", "2"}, <-messages)
+
+	t.Run("closes removed listeners with queued messages", func(t *testing.T) {
+		client.removeListener(stalled)
+
+		for range stalled {
+		}
+		assert.True(t, client.dispatch(orderStatus, []string{"3", "3"}))
+		assert.Equal(t, []string{"3", "3"}, <-messages)
+	})
+}
+
+func TestRequestChannels(t *testing.T) {
+	client := IbClient{}
+
+	stalled := client.addChannel(9000)
+	messages := client.addChannel(9001)
+
+	client.getChannel(9000).push([]string{"1", "2", "9000"})
+	client.getChannel(9001).push([]string{"1", "2", "9001"})
+	assert.Equal(t, []string{"1", "2", "9001"}, <-messages)
+
+	t.Run("closes removed channels while the reader still holds them", func(t *testing.T) {
+		receiver := client.getChannel(9000)
+		client.removeChannel(9000)
+		receiver.push([]string{"1", "2", "9000"})
+
+		for range stalled {
+		}
+		assert.Nil(t, client.getChannel(9000))
+	})
+}
+
+func TestHandleErrorMessage(t *testing.T) {
+	client := IbClient{channels: make(map[int]*listener)}
+
+	client.addChannel(9000) // a request with the same id
+	orders := client.addListener(errMsg)
+	defer client.removeListener(orders)
+
+	client.trackOrderId(9000)
+
+	fields := []string{"4", "2", "9000", "201", "Order rejected"}
+	client.handleErrorMessage(&parser{fields[1:]}, fields)
+
+	assert.Equal(t, fields, <-orders)
+
+	t.Run("drops the errors of removed requests", func(t *testing.T) {
+		fields := []string{"4", "2", "9001", "300", "Can't find EId with tickerId:9001"}
+		client.handleErrorMessage(&parser{fields[1:]}, fields)
+
+		fields = []string{"4", "2", "9000", "202", "Order Canceled"}
+		client.handleErrorMessage(&parser{fields[1:]}, fields)
+
+		assert.Equal(t, fields, <-orders)
+	})
+}
+
+func TestIsOrderWarning(t *testing.T) {
+	assert.True(t, isOrderWarning(399))
+	assert.True(t, isOrderWarning(2109))
+	assert.False(t, isOrderWarning(orderCancelled))
+	assert.False(t, isOrderWarning(10147))
+	assert.False(t, isOrderWarning(201))
+}
+
+// fakeBus records the packets written by the client.
+type fakeBus struct {
+	mu      sync.Mutex
+	packets []string
+}
+
+func (b *fakeBus) ReadPacket() (string, error) { select {} }
+func (b *fakeBus) Write(data string) error     { return nil }
+func (b *fakeBus) Close() error                { return nil }
+
+func (b *fakeBus) WritePacket(data string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.packets = append(b.packets, data)
+	return nil
+}
+
+func TestMarketDataError(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	ticks, err := client.MarketData(context.Background(), Contract{Symbol: "XYZ", SecurityType: "STK"}, nil, false, false)
+	assert.Nil(t, err)
+
+	client.getChannel(9000).push([]string{"4", "2", "9000", "10167", "Displaying delayed market data"})
+	client.getChannel(9000).push([]string{"4", "2", "9000", "200", "No security definition has been found for the request"})
+
+	assert.Equal(t, ErrorTick{Error: RequestError{RequestId: 9000, Code: 200, Message: "No security definition has been found for the request"}}, <-ticks)
+
+	_, ok := <-ticks
+	assert.False(t, ok)
+}
+
+func TestQuoteError(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	go func() {
+		for client.getChannel(9000) == nil {
+			time.Sleep(time.Millisecond)
+		}
+		client.getChannel(9000).push([]string{"4", "2", "9000", "354", "Requested market data is not subscribed"})
+	}()
+
+	quote, err := client.Quote(context.Background(), Contract{Symbol: "XYZ", SecurityType: "STK"})
+
+	assert.Equal(t, Quote{}, quote)
+	assert.Equal(t, RequestError{RequestId: 9000, Code: 354, Message: "Requested market data is not subscribed"}, err)
+}
+
+func TestMarketDepthError(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	events, err := client.MarketDepth(context.Background(), Contract{Symbol: "XYZ", SecurityType: "STK"}, 5, false)
+	assert.Nil(t, err)
+
+	client.getChannel(9000).push([]string{"4", "2", "9000", "317", "Market depth data has been RESET"})
+	assert.Equal(t, DepthEvent{Operation: DepthReset}, <-events)
+
+	client.getChannel(9000).push([]string{"4", "2", "9000", "354", "Requested market data is not subscribed"})
+	assert.Equal(t, DepthEvent{Operation: DepthError, Error: &RequestError{RequestId: 9000, Code: 354, Message: "Requested market data is not subscribed"}}, <-events)
+
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestHistoricalDataUpdates(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	go func() {
+		for client.getChannel(9000) == nil {
+			time.Sleep(time.Millisecond)
+		}
+		client.getChannel(9000).push([]string{"17", "9000", "20220610 09:30:00", "20220610 09:35:00", "1", "1654867800", "151.25", "152.5", "150.75", "152", "12345", "151.6", "100"})
+	}()
+
+	bars, updates, err := client.HistoricalDataUpdates(context.Background(), Contract{Symbol: "AAPL", SecurityType: "STK"}, Seconds(300), BarSize5Mins, WhatToShowTrades, true)
+	assert.Nil(t, err)
+	assert.Len(t, bars, 1)
+
+	client.getChannel(9000).push([]string{"90", "9000", "101", "1654867800", "151.25", "152.75", "152.75", "150.75", "151.7", "12400"})
+	update := <-updates
+	assert.False(t, update.NewBar)
+	assert.Equal(t, 152.75, update.Bar.Close)
+
+	client.getChannel(9000).push([]string{"90", "9000", "3", "1654868100", "152.75", "152.8", "152.8", "152.75", "152.78", "300"})
+	assert.True(t, (<-updates).NewBar)
+
+	client.getChannel(9000).push([]string{"4", "2", "9000", "162", "Historical Market Data Service error message"})
+	assert.Equal(t, BarUpdate{Error: &RequestError{RequestId: 9000, Code: 162, Message: "Historical Market Data Service error message"}}, <-updates)
+
+	_, ok := <-updates
+	assert.False(t, ok)
+}
+
+func TestFaGroupsError(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	go func() {
+		fields := []string{"4", "2", "-1", "321", "Error validating request:-'bB' : cause - FA data operations ignored for non FA customers."}
+		for {
+			client.listenersMutex.Lock()
+			listening := len(client.listeners[receiveFa]) > 0
+			client.listenersMutex.Unlock()
+
+			if listening {
+				client.handleErrorMessage(&parser{fields[1:]}, fields)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	_, err := client.FaGroups(context.Background())
+
+	assert.Equal(t, RequestError{RequestId: noRequest, Code: 321, Message: "Error validating request:-'bB' : cause - FA data operations ignored for non FA customers."}, err)
+}
+
+func TestPositionMultiUpdates(t *testing.T) {
+	bus := &fakeBus{}
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: bus, channels: make(map[int]*listener)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	positions, err := client.PositionMultiUpdates(ctx, "DU1234", "")
+	assert.Nil(t, err)
+
+	client.getChannel(9000).push([]string{"71", "1", "9000", "DU1234", "265598", "AAPL", "STK", "", "0.0", "", "", "NASDAQ", "USD", "AAPL", "NMS", "100", "150.25", ""})
+	assert.Equal(t, 100.0, (<-positions).Quantity)
+	client.getChannel(9000).push([]string{"72", "1", "9000"})
+
+	cancel()
+
+	_, ok := <-positions
+	assert.False(t, ok)
+	assert.Nil(t, client.getChannel(9000))
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	assert.Equal(t, "75\x001\x009000\x00", bus.packets[len(bus.packets)-1])
+}
+
+func TestExecutionsError(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}, channels: make(map[int]*listener)}
+
+	go func() {
+		for client.getChannel(9000) == nil {
+			time.Sleep(time.Millisecond)
+		}
+		client.getChannel(9000).push([]string{"4", "2", "9000", "321", "Error validating request"})
+	}()
+
+	executions, err := client.Executions(context.Background(), ExecutionFilter{})
+
+	assert.Empty(t, executions)
+	assert.Equal(t, RequestError{RequestId: 9000, Code: 321, Message: "Error validating request"}, err)
+	assert.Nil(t, client.getChannel(9000))
+}
+
+func TestExecutionReports(t *testing.T) {
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: &fakeBus{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	executions, err := client.ExecutionReports(ctx)
+	assert.Nil(t, err)
+
+	client.dispatch(executionData, []string{
+		"11", "-1", "13",
+		"495512551", "ES", "FUT", "20220916", "0", "", "50", "GLOBEX", "USD", "ESU2", "ES",
+		"0000e0d5.62a2a8a4.01.01", "20220610  10:15:01", "DU1234", "GLOBEX", "BOT", "2", "4100.25", "1376327563", "100", "0",
+		"2", "4100.25", "ref", "", "", "", "2",
+	})
+	execution := <-executions
+	assert.Equal(t, "0000e0d5.62a2a8a4.01.01", execution.ExecId)
+	assert.Equal(t, 13, execution.OrderId)
+	assert.Equal(t, CommissionReport{}, execution.CommissionReport)
+
+	// the report of an execution sent before the stream started
+	client.dispatch(commissionReport, []string{"59", "1", "0000e0d5.62a2a8a4.01.00", "4.24", "USD", "1.7976931348623157E308", "1.7976931348623157E308", ""})
+	execution = <-executions
+	assert.Equal(t, "0000e0d5.62a2a8a4.01.00", execution.ExecId)
+	assert.Equal(t, 4.24, execution.CommissionReport.Commission)
+}
+
+func TestPlaceOrderCancelled(t *testing.T) {
+	bus := &fakeBus{}
+	client := IbClient{ServerVersion: maxClientVer, MessageBus: bus}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.PlaceOrder(ctx, 13, Contract{Symbol: "AAPL", SecurityType: "STK"}, NewOrder())
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Empty(t, bus.packets)
+	assert.False(t, client.isOrderId(13))
+}
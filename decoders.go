@@ -3,7 +3,13 @@ package ibapi
 // Decoders convert raw messages into a structured responses
 
 import (
+	"encoding/xml"
+	"fmt"
+	"io"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -139,3 +145,1304 @@ func decodeContractDetails(serverVersion int, fields []string) ContractDetails {
 
 	return details
 }
+
+// decodeOrderStatus converts an OrderStatus incoming message into an OrderStatus
+func decodeOrderStatus(serverVersion int, fields []string) OrderStatus {
+	scanner := &parser{fields[1:]}
+
+	if serverVersion < minServerVerMarketCapPrice {
+		scanner.readInt() // version
+	}
+
+	status := OrderStatus{}
+
+	status.OrderId = scanner.readInt()
+	status.Status = scanner.readString()
+	status.Filled = scanner.readFloat64()
+	status.Remaining = scanner.readFloat64()
+	status.AverageFillPrice = scanner.readFloat64()
+	status.PermId = scanner.readInt()
+	status.ParentId = scanner.readInt()
+	status.LastFillPrice = scanner.readFloat64()
+	status.ClientId = scanner.readInt()
+	status.WhyHeld = scanner.readString()
+
+	if serverVersion >= minServerVerMarketCapPrice {
+		status.MarketCapPrice = scanner.readFloat64()
+	}
+
+	return status
+}
+
+// decodeOrderError converts an error message for an order into an OrderError
+func decodeOrderError(fields []string) OrderError {
+	scanner := &parser{fields[2:]}
+
+	return OrderError{
+		OrderId: scanner.readInt(),
+		Code:    scanner.readInt(),
+		Message: scanner.readString(),
+	}
+}
+
+// decodeRequestError converts an error message for a request into a RequestError
+func decodeRequestError(fields []string) RequestError {
+	scanner := &parser{fields[2:]}
+
+	return RequestError{
+		RequestId: scanner.readInt(),
+		Code:      scanner.readInt(),
+		Message:   scanner.readString(),
+	}
+}
+
+// decodeAccountSummary converts an AccountSummary incoming message into an AccountSummaryValue
+func decodeAccountSummary(fields []string) AccountSummaryValue {
+	scanner := &parser{fields[3:]}
+
+	return AccountSummaryValue{
+		Account:  scanner.readString(),
+		Tag:      AccountSummaryTag(scanner.readString()),
+		Value:    scanner.readString(),
+		Currency: scanner.readString(),
+	}
+}
+
+// decodePosition converts a PositionData incoming message into a Position
+func decodePosition(fields []string) Position {
+	scanner := &parser{fields[1:]}
+
+	version := scanner.readInt()
+	position := Position{Account: scanner.readString()}
+
+	position.Contract.ContractId = scanner.readInt()
+	position.Contract.Symbol = scanner.readString()
+	position.Contract.SecurityType = scanner.readString()
+	position.Contract.LastTradeDateOrContractMonth = scanner.readString()
+	position.Contract.Strike = scanner.readFloat64()
+	position.Contract.Right = scanner.readString()
+	position.Contract.Multiplier = scanner.readString()
+	position.Contract.Exchange = scanner.readString()
+	position.Contract.Currency = scanner.readString()
+	position.Contract.LocalSymbol = scanner.readString()
+	if version >= 2 {
+		position.Contract.TradingClass = scanner.readString()
+	}
+
+	position.Quantity = scanner.readFloat64()
+	if version >= 3 {
+		position.AvgCost = scanner.readFloat64()
+	}
+
+	return position
+}
+
+// decodePositionMulti converts a PositionMulti incoming message into a Position
+func decodePositionMulti(fields []string) Position {
+	scanner := &parser{fields[3:]}
+
+	position := Position{Account: scanner.readString()}
+
+	position.Contract.ContractId = scanner.readInt()
+	position.Contract.Symbol = scanner.readString()
+	position.Contract.SecurityType = scanner.readString()
+	position.Contract.LastTradeDateOrContractMonth = scanner.readString()
+	position.Contract.Strike = scanner.readFloat64()
+	position.Contract.Right = scanner.readString()
+	position.Contract.Multiplier = scanner.readString()
+	position.Contract.Exchange = scanner.readString()
+	position.Contract.Currency = scanner.readString()
+	position.Contract.LocalSymbol = scanner.readString()
+	position.Contract.TradingClass = scanner.readString()
+	position.Quantity = scanner.readFloat64()
+	position.AvgCost = scanner.readFloat64()
+	position.ModelCode = scanner.readString()
+
+	return position
+}
+
+// decodeAccountUpdateMulti converts an AccountUpdateMulti incoming message into an AccountValue
+func decodeAccountUpdateMulti(fields []string) AccountValue {
+	scanner := &parser{fields[3:]}
+
+	return AccountValue{
+		Account:   scanner.readString(),
+		ModelCode: scanner.readString(),
+		Key:       scanner.readString(),
+		Value:     scanner.readString(),
+		Currency:  scanner.readString(),
+	}
+}
+
+// decodeAccountValue converts an AccountValue incoming message into an AccountValue
+func decodeAccountValue(fields []string) AccountValue {
+	scanner := &parser{fields[1:]}
+
+	version := scanner.readInt()
+	value := AccountValue{
+		Key:      scanner.readString(),
+		Value:    scanner.readString(),
+		Currency: scanner.readString(),
+	}
+	if version >= 2 {
+		value.Account = scanner.readString()
+	}
+
+	return value
+}
+
+// decodePortfolioValue converts a PortfolioValue incoming message into a PortfolioItem
+func decodePortfolioValue(fields []string) PortfolioItem {
+	scanner := &parser{fields[1:]}
+
+	version := scanner.readInt()
+	item := PortfolioItem{}
+
+	if version >= 6 {
+		item.Contract.ContractId = scanner.readInt()
+	}
+	item.Contract.Symbol = scanner.readString()
+	item.Contract.SecurityType = scanner.readString()
+	item.Contract.LastTradeDateOrContractMonth = scanner.readString()
+	item.Contract.Strike = scanner.readFloat64()
+	item.Contract.Right = scanner.readString()
+	if version >= 7 {
+		item.Contract.Multiplier = scanner.readString()
+		item.Contract.PrimaryExchange = scanner.readString()
+	}
+	item.Contract.Currency = scanner.readString()
+	item.Contract.LocalSymbol = scanner.readString()
+	if version >= 8 {
+		item.Contract.TradingClass = scanner.readString()
+	}
+
+	item.Position = scanner.readFloat64()
+	item.MarketPrice = scanner.readFloat64()
+	item.MarketValue = scanner.readFloat64()
+	if version >= 3 {
+		item.AverageCost = scanner.readFloat64()
+		item.UnrealizedPnL = scanner.readFloat64()
+		item.RealizedPnL = scanner.readFloat64()
+	}
+	if version >= 4 {
+		item.Account = scanner.readString()
+	}
+
+	return item
+}
+
+// decodeAccountUpdate converts an account value, portfolio value, account update time or account download end incoming message into an AccountUpdate
+func decodeAccountUpdate(fields []string) (AccountUpdate, bool) {
+	fieldsId, err := strconv.Atoi(fields[0])
+	if err != nil {
+		log.Printf("error parsing fieldsId [%s]: %v", fields[0], err)
+	}
+
+	switch fieldsId {
+	case accountValue:
+		value := decodeAccountValue(fields)
+		return AccountUpdate{AccountValue: &value}, true
+	case portfolioValue:
+		item := decodePortfolioValue(fields)
+		return AccountUpdate{PortfolioItem: &item}, true
+	case accountUpdateTime:
+		return AccountUpdate{UpdateTime: fields[2]}, true
+	case accountDownloadEnd:
+		return AccountUpdate{DownloadEnd: true}, true
+	default:
+		log.Printf("unexpected fields: %v", fields)
+		return AccountUpdate{}, false
+	}
+}
+
+// decodePnL converts a PnL incoming message into a PnL
+func decodePnL(serverVersion int, fields []string) PnL {
+	scanner := &parser{fields[2:]}
+
+	pnl := PnL{DailyPnL: scanner.readFloat64()}
+	if serverVersion >= minServerVerUnrealizedPnl {
+		pnl.UnrealizedPnL = scanner.readFloat64()
+	}
+	if serverVersion >= minServerVerRealizedPnl {
+		pnl.RealizedPnL = scanner.readFloat64()
+	}
+
+	return pnl
+}
+
+// decodePnLSingle converts a PnLSingle incoming message into a PnLSingle
+func decodePnLSingle(serverVersion int, fields []string) PnLSingle {
+	scanner := &parser{fields[2:]}
+
+	pnl := PnLSingle{
+		Position: scanner.readFloat64(),
+		DailyPnL: scanner.readFloat64(),
+	}
+	if serverVersion >= minServerVerUnrealizedPnl {
+		pnl.UnrealizedPnL = scanner.readFloat64()
+	}
+	if serverVersion >= minServerVerRealizedPnl {
+		pnl.RealizedPnL = scanner.readFloat64()
+	}
+	pnl.Value = scanner.readFloat64()
+
+	return pnl
+}
+
+// decodeManagedAccounts converts a ManagedAccounts incoming message into the list of account ids
+func decodeManagedAccounts(fields []string) []string {
+	scanner := &parser{fields[2:]}
+
+	accounts := []string{}
+	for _, account := range strings.Split(scanner.readString(), ",") {
+		if account = strings.TrimSpace(account); account != "" {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts
+}
+
+// decodeTickPrice converts a TickPrice incoming message into a PriceTick
+func decodeTickPrice(serverVersion int, fields []string) PriceTick {
+	scanner := &parser{fields[1:]}
+
+	version := scanner.readInt()
+	scanner.readInt() // request id
+
+	tick := PriceTick{
+		TickType: TickType(scanner.readInt()),
+		Price:    scanner.readFloat64(),
+	}
+	if version >= 2 {
+		tick.Size = scanner.readFloat64()
+	}
+	if version >= 3 {
+		mask := scanner.readInt()
+		if serverVersion >= minServerVerPastLimit {
+			tick.Attributes.CanAutoExecute = mask&0x1 == 0x1
+			tick.Attributes.PastLimit = mask&0x2 == 0x2
+			if serverVersion >= minServerVerPreOpenBidAsk {
+				tick.Attributes.PreOpen = mask&0x4 == 0x4
+			}
+		} else {
+			tick.Attributes.CanAutoExecute = mask == 1
+		}
+	}
+
+	return tick
+}
+
+// decodeTickSize converts a TickSize incoming message into a SizeTick
+func decodeTickSize(fields []string) SizeTick {
+	scanner := &parser{fields[3:]}
+
+	return SizeTick{
+		TickType: TickType(scanner.readInt()),
+		Size:     scanner.readFloat64(),
+	}
+}
+
+// decodeTickString converts a TickString incoming message into a StringTick
+func decodeTickString(fields []string) StringTick {
+	scanner := &parser{fields[3:]}
+
+	return StringTick{
+		TickType: TickType(scanner.readInt()),
+		Value:    scanner.readString(),
+	}
+}
+
+// decodeTickGeneric converts a TickGeneric incoming message into a GenericTick
+func decodeTickGeneric(fields []string) GenericTick {
+	scanner := &parser{fields[3:]}
+
+	return GenericTick{
+		TickType: TickType(scanner.readInt()),
+		Value:    scanner.readFloat64(),
+	}
+}
+
+// decodeTickEfp converts a TickEfp incoming message into an EfpTick
+func decodeTickEfp(fields []string) EfpTick {
+	scanner := &parser{fields[3:]}
+
+	return EfpTick{
+		TickType:                 TickType(scanner.readInt()),
+		BasisPoints:              scanner.readFloat64(),
+		FormattedBasisPoints:     scanner.readString(),
+		ImpliedFuturesPrice:      scanner.readFloat64(),
+		HoldDays:                 scanner.readInt(),
+		FutureLastTradeDate:      scanner.readString(),
+		DividendImpact:           scanner.readFloat64(),
+		DividendsToLastTradeDate: scanner.readFloat64(),
+	}
+}
+
+// decodeMarketDataType converts a MarketDataType incoming message into a MarketDataTypeTick
+func decodeMarketDataType(fields []string) MarketDataTypeTick {
+	scanner := &parser{fields[3:]}
+
+	return MarketDataTypeTick{MarketDataType: MarketDataType(scanner.readInt())}
+}
+
+// decodeTickRequestParameters converts a TickRequestParameters incoming message into a RequestParametersTick
+func decodeTickRequestParameters(fields []string) RequestParametersTick {
+	scanner := &parser{fields[2:]}
+
+	return RequestParametersTick{
+		MinTick:             scanner.readFloat64(),
+		BboExchange:         scanner.readString(),
+		SnapshotPermissions: scanner.readInt(),
+	}
+}
+
+// decodeTickOptionComputation converts a TickOptionComputation incoming message into an OptionComputationTick
+func decodeTickOptionComputation(serverVersion int, fields []string) OptionComputationTick {
+	scanner := &parser{fields[1:]}
+
+	version := serverVersion
+	if serverVersion < minServerVerPriceBasedVolatility {
+		version = scanner.readInt()
+	}
+	scanner.readInt() // request id
+
+	tick := OptionComputationTick{TickType: TickType(scanner.readInt())}
+	if serverVersion >= minServerVerPriceBasedVolatility {
+		tick.PriceBased = scanner.readInt() == 1
+	}
+
+	// values that could not be computed are sent as -1, or -2 for values that can be negative
+	notComputed := func(value float64, sentinel float64) float64 {
+		if value == sentinel {
+			return math.MaxFloat64
+		}
+		return value
+	}
+
+	tick.ImpliedVolatility = scanner.readFloat64()
+	if tick.ImpliedVolatility < 0 {
+		tick.ImpliedVolatility = math.MaxFloat64
+	}
+	tick.Delta = notComputed(scanner.readFloat64(), -2)
+
+	tick.OptionPrice, tick.PvDividend = math.MaxFloat64, math.MaxFloat64
+	if version >= 6 || tick.TickType == TickModelOptionComputation || tick.TickType == TickDelayedModelOption {
+		tick.OptionPrice = notComputed(scanner.readFloat64(), -1)
+		tick.PvDividend = notComputed(scanner.readFloat64(), -1)
+	}
+
+	tick.Gamma, tick.Vega, tick.Theta, tick.UnderlyingPrice = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	if version >= 6 {
+		tick.Gamma = notComputed(scanner.readFloat64(), -2)
+		tick.Vega = notComputed(scanner.readFloat64(), -2)
+		tick.Theta = notComputed(scanner.readFloat64(), -2)
+		tick.UnderlyingPrice = notComputed(scanner.readFloat64(), -1)
+	}
+
+	return tick
+}
+
+// decodeMarketDepth converts a MarketDepth incoming message into a DepthEvent
+func decodeMarketDepth(fields []string) DepthEvent {
+	scanner := &parser{fields[3:]}
+
+	return DepthEvent{
+		Position:  scanner.readInt(),
+		Operation: DepthOperation(scanner.readInt()),
+		Side:      scanner.readInt(),
+		Price:     scanner.readFloat64(),
+		Size:      scanner.readFloat64(),
+	}
+}
+
+// decodeMarketDepthL2 converts a MarketDepthL2 incoming message into a DepthEvent
+func decodeMarketDepthL2(serverVersion int, fields []string) DepthEvent {
+	scanner := &parser{fields[3:]}
+
+	event := DepthEvent{
+		Position:    scanner.readInt(),
+		MarketMaker: scanner.readString(),
+		Operation:   DepthOperation(scanner.readInt()),
+		Side:        scanner.readInt(),
+		Price:       scanner.readFloat64(),
+		Size:        scanner.readFloat64(),
+	}
+	if serverVersion >= minServerVerSmartDepth {
+		event.SmartDepth = scanner.readBool()
+	}
+
+	return event
+}
+
+// decodeMarketDepthExchanges converts a MarketDepthExchanges incoming message into DepthExchanges
+func decodeMarketDepthExchanges(serverVersion int, fields []string) []DepthExchange {
+	scanner := &parser{fields[1:]}
+
+	count := scanner.readInt()
+	exchanges := make([]DepthExchange, count)
+
+	for i := range exchanges {
+		exchanges[i].Exchange = scanner.readString()
+		exchanges[i].SecurityType = scanner.readString()
+		if serverVersion >= minServerVerServiceDataType {
+			exchanges[i].ListingExchange = scanner.readString()
+			exchanges[i].ServiceDataType = scanner.readString()
+			exchanges[i].AggregatedGroup = scanner.readInt()
+		} else if scanner.readBool() {
+			exchanges[i].ServiceDataType = "Deep2"
+		} else {
+			exchanges[i].ServiceDataType = "Deep"
+		}
+	}
+
+	return exchanges
+}
+
+// decodeSmartComponents converts a SmartComponents incoming message into SmartComponents
+func decodeSmartComponents(fields []string) []SmartComponent {
+	scanner := &parser{fields[2:]}
+
+	count := scanner.readInt()
+	components := make([]SmartComponent, count)
+
+	for i := range components {
+		components[i].BitNumber = scanner.readInt()
+		components[i].Exchange = scanner.readString()
+		components[i].ExchangeLetter = scanner.readString()
+	}
+
+	return components
+}
+
+// decodeExecution converts an ExecutionData incoming message into an Execution
+func decodeExecution(serverVersion int, fields []string) Execution {
+	scanner := &parser{fields[1:]}
+
+	version := serverVersion
+	if serverVersion < minServerVerLastLiquidity {
+		version = scanner.readInt()
+	}
+
+	if version >= 7 {
+		scanner.readInt() // request id
+	}
+
+	execution := Execution{}
+
+	execution.OrderId = scanner.readInt()
+
+	execution.Contract.ContractId = scanner.readInt()
+	execution.Contract.Symbol = scanner.readString()
+	execution.Contract.SecurityType = scanner.readString()
+	execution.Contract.LastTradeDateOrContractMonth = scanner.readString()
+	execution.Contract.Strike = scanner.readFloat64()
+	execution.Contract.Right = scanner.readString()
+	if version >= 9 {
+		execution.Contract.Multiplier = scanner.readString()
+	}
+	execution.Contract.Exchange = scanner.readString()
+	execution.Contract.Currency = scanner.readString()
+	execution.Contract.LocalSymbol = scanner.readString()
+	if version >= 10 {
+		execution.Contract.TradingClass = scanner.readString()
+	}
+
+	execution.ExecId = scanner.readString()
+	execution.Time = scanner.readString()
+	execution.Account = scanner.readString()
+	execution.Exchange = scanner.readString()
+	execution.Side = scanner.readString()
+	execution.Shares = scanner.readFloat64()
+	execution.Price = scanner.readFloat64()
+	execution.PermId = scanner.readInt()
+	execution.ClientId = scanner.readInt()
+	execution.Liquidation = scanner.readInt()
+
+	if version >= 6 {
+		execution.CumulativeQuantity = scanner.readFloat64()
+		execution.AveragePrice = scanner.readFloat64()
+	}
+
+	if version >= 8 {
+		execution.OrderRef = scanner.readString()
+	}
+
+	if version >= 9 {
+		execution.EvRule = scanner.readString()
+		execution.EvMultiplier = scanner.readFloat64()
+	}
+
+	if serverVersion >= minServerVerModelsSupport {
+		execution.ModelCode = scanner.readString()
+	}
+
+	if serverVersion >= minServerVerLastLiquidity {
+		execution.LastLiquidity = scanner.readInt()
+	}
+
+	return execution
+}
+
+// decodeCommissionReport converts a CommissionReport incoming message into a CommissionReport
+func decodeCommissionReport(fields []string) CommissionReport {
+	scanner := &parser{fields[2:]}
+
+	return CommissionReport{
+		ExecId:              scanner.readString(),
+		Commission:          scanner.readFloat64(),
+		Currency:            scanner.readString(),
+		RealizedPnL:         scanner.readFloat64(),
+		Yield:               scanner.readFloat64(),
+		YieldRedemptionDate: scanner.readInt(),
+	}
+}
+
+// decodeOpenOrder converts an OpenOrder incoming message into an OpenOrder
+func decodeOpenOrder(serverVersion int, fields []string) OpenOrder {
+	decoder := orderDecoder{
+		scanner:       &parser{fields[1:]},
+		serverVersion: serverVersion,
+		version:       serverVersion,
+	}
+
+	if serverVersion < minServerVerOrderContainer {
+		decoder.version = decoder.scanner.readInt()
+	}
+
+	decoder.order.OrderId = decoder.scanner.readInt()
+	decoder.decodeContractFields()
+	decoder.decodeMainOrderFields()
+	decoder.order.ClientId = decoder.scanner.readInt()
+	decoder.order.PermId = decoder.scanner.readInt()
+	decoder.decodeExtendedOrderFields(true)
+	decoder.order.BlockOrder = decoder.scanner.readBool()
+	decoder.order.SweepToFill = decoder.scanner.readBool()
+	decoder.order.AllOrNone = decoder.scanner.readBool()
+	decoder.order.MinQuantity = decoder.scanner.readInt()
+	decoder.order.OcaType = decoder.scanner.readInt()
+	decoder.scanner.readBool()    // deprecated eTradeOnly
+	decoder.scanner.readBool()    // deprecated firmQuoteOnly
+	decoder.scanner.readFloat64() // deprecated nbboPriceCap
+	decoder.order.ParentId = decoder.scanner.readInt()
+	decoder.order.TriggerMethod = decoder.scanner.readInt()
+	decoder.decodeVolatilityOrderFields(true)
+	decoder.decodeTrailFields()
+	decoder.scanner.readFloat64() // basis points
+	decoder.scanner.readInt()     // basis points type
+	decoder.decodeComboLegs()
+	decoder.decodeScaleOrderFields()
+	decoder.decodeHedgeFields()
+	if decoder.version >= 25 {
+		decoder.order.OptOutSmartRouting = decoder.scanner.readBool()
+	}
+	decoder.decodeClearingFields()
+	decoder.decodeDeltaNeutralContract()
+	decoder.decodeAlgoFields()
+	if decoder.version >= 33 {
+		decoder.order.Solicited = decoder.scanner.readBool()
+	}
+	decoder.order.WhatIf = decoder.scanner.readBool()
+	decoder.orderState.Status = decoder.scanner.readString()
+	decoder.decodeMarginAndCommission()
+	decoder.decodeRandomizeFlags()
+	if !decoder.decodeConditions() {
+		return decoder.openOrder()
+	}
+	decoder.decodeAdjustedOrderFields()
+	if serverVersion >= minServerVerSoftDollarTier {
+		decoder.scanner.readString() // soft dollar tier name
+		decoder.scanner.readString() // soft dollar tier value
+		decoder.scanner.readString() // soft dollar tier display name
+	}
+	if serverVersion >= minServerVerCashQty {
+		decoder.order.CashQuantity = decoder.scanner.readFloat64()
+	}
+	if serverVersion >= minServerVerAutoPriceForHedge {
+		decoder.order.DontUseAutoPriceForHedge = decoder.scanner.readBool()
+	}
+	if serverVersion >= minServerVerOrderContainer {
+		decoder.order.IsOmsContainer = decoder.scanner.readBool()
+	}
+	if serverVersion >= minServerVerDPegOrders {
+		decoder.scanner.readBool() // discretionary up to limit price
+	}
+	if serverVersion >= minServerVerPriceMgmtAlgo {
+		decoder.order.UsePriceMgmtAlgo = decoder.scanner.readBool()
+	}
+
+	return decoder.openOrder()
+}
+
+// decodeCompletedOrder converts a CompletedOrder incoming message into a CompletedOrder
+func decodeCompletedOrder(serverVersion int, fields []string) CompletedOrder {
+	decoder := orderDecoder{
+		scanner:       &parser{fields[1:]},
+		serverVersion: serverVersion,
+		version:       math.MaxInt32, // completed orders are not versioned, all fields are present
+	}
+
+	decoder.decodeContractFields()
+	decoder.decodeMainOrderFields()
+	decoder.order.PermId = decoder.scanner.readInt()
+	decoder.decodeExtendedOrderFields(false)
+	decoder.order.SweepToFill = decoder.scanner.readBool()
+	decoder.order.AllOrNone = decoder.scanner.readBool()
+	decoder.order.MinQuantity = decoder.scanner.readInt()
+	decoder.order.OcaType = decoder.scanner.readInt()
+	decoder.order.TriggerMethod = decoder.scanner.readInt()
+	decoder.decodeVolatilityOrderFields(false)
+	decoder.decodeTrailFields()
+	decoder.decodeComboLegs()
+	decoder.decodeScaleOrderFields()
+	decoder.decodeHedgeFields()
+	decoder.decodeClearingFields()
+	decoder.decodeDeltaNeutralContract()
+	decoder.decodeAlgoFields()
+	decoder.order.Solicited = decoder.scanner.readBool()
+	decoder.orderState.Status = decoder.scanner.readString()
+	decoder.decodeRandomizeFlags()
+	if !decoder.decodeConditions() {
+		return decoder.completedOrder()
+	}
+	decoder.decodeStopPriceAndLimitPriceOffset()
+	if serverVersion >= minServerVerCashQty {
+		decoder.order.CashQuantity = decoder.scanner.readFloat64()
+	}
+	if serverVersion >= minServerVerAutoPriceForHedge {
+		decoder.order.DontUseAutoPriceForHedge = decoder.scanner.readBool()
+	}
+	if serverVersion >= minServerVerOrderContainer {
+		decoder.order.IsOmsContainer = decoder.scanner.readBool()
+	}
+	decoder.scanner.readString() // auto cancel date
+	decoder.order.FilledQuantity = decoder.scanner.readFloat64()
+	decoder.scanner.readInt()    // reference futures contract id
+	decoder.scanner.readBool()   // auto cancel parent
+	decoder.scanner.readString() // shareholder
+	decoder.scanner.readBool()   // imbalance only
+	decoder.scanner.readBool()   // route marketable to BBO
+	decoder.order.ParentPermId = decoder.scanner.readInt()
+	decoder.orderState.CompletedTime = decoder.scanner.readString()
+	decoder.orderState.CompletedStatus = decoder.scanner.readString()
+
+	return decoder.completedOrder()
+}
+
+// orderDecoder reads the contract, order and order state fields shared by the order messages.
+type orderDecoder struct {
+	scanner       *parser
+	serverVersion int
+	version       int
+
+	contract   Contract
+	order      Order
+	orderState OrderState
+}
+
+func (d *orderDecoder) openOrder() OpenOrder {
+	return OpenOrder{
+		Contract:   d.contract,
+		Order:      d.order,
+		OrderState: d.orderState,
+	}
+}
+
+func (d *orderDecoder) completedOrder() CompletedOrder {
+	return CompletedOrder{
+		Contract:   d.contract,
+		Order:      d.order,
+		OrderState: d.orderState,
+	}
+}
+
+func (d *orderDecoder) decodeContractFields() {
+	d.contract.ContractId = d.scanner.readInt()
+	d.contract.Symbol = d.scanner.readString()
+	d.contract.SecurityType = d.scanner.readString()
+	d.contract.LastTradeDateOrContractMonth = d.scanner.readString()
+	d.contract.Strike = d.scanner.readFloat64()
+	d.contract.Right = d.scanner.readString()
+	if d.version >= 32 {
+		d.contract.Multiplier = d.scanner.readString()
+	}
+	d.contract.Exchange = d.scanner.readString()
+	d.contract.Currency = d.scanner.readString()
+	d.contract.LocalSymbol = d.scanner.readString()
+	if d.version >= 32 {
+		d.contract.TradingClass = d.scanner.readString()
+	}
+}
+
+// decodeMainOrderFields reads the fields from action up to the order reference.
+func (d *orderDecoder) decodeMainOrderFields() {
+	d.order.Action = d.scanner.readString()
+	d.order.TotalQuantity = d.scanner.readFloat64()
+	d.order.OrderType = d.scanner.readString()
+	d.order.LimitPrice = d.scanner.readFloat64()
+	d.order.AuxPrice = d.scanner.readFloat64()
+	d.order.TimeInForce = d.scanner.readString()
+	d.order.OcaGroup = d.scanner.readString()
+	d.order.Account = d.scanner.readString()
+	d.order.OpenClose = d.scanner.readString()
+	d.order.Origin = d.scanner.readInt()
+	d.order.OrderRef = d.scanner.readString()
+}
+
+// decodeExtendedOrderFields reads the fields from outside RTH up to the display size.
+// The shares allocation and auction strategy fields are only present in open order messages.
+func (d *orderDecoder) decodeExtendedOrderFields(openOrderAttributes bool) {
+	d.order.OutsideRth = d.scanner.readBool()
+	d.order.Hidden = d.scanner.readBool()
+	d.order.DiscretionaryAmount = d.scanner.readFloat64()
+	d.order.GoodAfterTime = d.scanner.readString()
+	if openOrderAttributes {
+		d.scanner.readString() // deprecated shares allocation
+	}
+	d.decodeFinancialAdvisorFields()
+	d.order.GoodTillDate = d.scanner.readString()
+	d.order.Rule80A = d.scanner.readString()
+	d.order.PercentOffset = d.scanner.readFloat64()
+	d.order.SettlingFirm = d.scanner.readString()
+	d.decodeShortSaleFields()
+	if openOrderAttributes {
+		d.scanner.readInt() // auction strategy
+	}
+	d.decodeBoxAndPegToStockFields()
+	d.order.DisplaySize = d.scanner.readInt()
+}
+
+func (d *orderDecoder) decodeFinancialAdvisorFields() {
+	d.order.FaGroup = d.scanner.readString()
+	d.order.FaMethod = d.scanner.readString()
+	d.order.FaPercentage = d.scanner.readString()
+	d.order.FaProfile = d.scanner.readString()
+	if d.serverVersion >= minServerVerModelsSupport {
+		d.order.ModelCode = d.scanner.readString()
+	}
+}
+
+func (d *orderDecoder) decodeShortSaleFields() {
+	d.order.ShortSaleSlot = d.scanner.readInt()
+	d.order.DesignatedLocation = d.scanner.readString()
+	if d.serverVersion == minServerVerSshortxOld {
+		d.scanner.readInt()
+	} else if d.version >= 23 {
+		d.order.ExemptCode = d.scanner.readInt()
+	}
+}
+
+func (d *orderDecoder) decodeBoxAndPegToStockFields() {
+	d.scanner.readFloat64() // starting price
+	d.scanner.readFloat64() // stock reference price
+	d.scanner.readFloat64() // delta
+	d.scanner.readFloat64() // stock range lower
+	d.scanner.readFloat64() // stock range upper
+}
+
+// decodeVolatilityOrderFields reads the volatility and delta neutral fields.
+// The delta neutral clearing attributes are only present in open order messages.
+func (d *orderDecoder) decodeVolatilityOrderFields(openOrderAttributes bool) {
+	d.order.Volatility = d.scanner.readFloat64()
+	d.order.VolatilityType = d.scanner.readInt()
+
+	deltaNeutralOrderType := d.scanner.readString()
+	d.scanner.readFloat64() // delta neutral aux price
+
+	if d.version >= 27 && deltaNeutralOrderType != "" {
+		d.scanner.readInt() // delta neutral contract id
+		if openOrderAttributes {
+			d.scanner.readString() // delta neutral settling firm
+			d.scanner.readString() // delta neutral clearing account
+			d.scanner.readString() // delta neutral clearing intent
+		}
+	}
+
+	if d.version >= 31 && deltaNeutralOrderType != "" {
+		if openOrderAttributes {
+			d.scanner.readString() // delta neutral open close
+		}
+		d.scanner.readBool()   // delta neutral short sale
+		d.scanner.readInt()    // delta neutral short sale slot
+		d.scanner.readString() // delta neutral designated location
+	}
+
+	d.scanner.readBool() // continuous update
+	d.scanner.readInt()  // reference price type
+}
+
+func (d *orderDecoder) decodeTrailFields() {
+	d.order.TrailStopPrice = d.scanner.readFloat64()
+	if d.version >= 30 {
+		d.order.TrailingPercent = d.scanner.readFloat64()
+	}
+}
+
+func (d *orderDecoder) decodeComboLegs() {
+	d.contract.ComboLegsDescription = d.scanner.readString()
+
+	if d.version >= 29 {
+		comboLegsCount := d.scanner.readInt()
+		for i := 0; i < comboLegsCount; i++ {
+			leg := ComboLeg{}
+			leg.ContractId = d.scanner.readInt()
+			leg.Ratio = d.scanner.readInt()
+			leg.Action = d.scanner.readString()
+			leg.Exchange = d.scanner.readString()
+			leg.OpenClose = d.scanner.readInt()
+			leg.ShortSaleSlot = d.scanner.readInt()
+			leg.DesignatedLocation = d.scanner.readString()
+			leg.ExemptCode = d.scanner.readInt()
+			d.contract.ComboLegs = append(d.contract.ComboLegs, leg)
+		}
+
+		orderComboLegsCount := d.scanner.readInt()
+		for i := 0; i < orderComboLegsCount; i++ {
+			leg := OrderComboLeg{Price: d.scanner.readFloat64()}
+			d.order.OrderComboLegs = append(d.order.OrderComboLegs, leg)
+		}
+	}
+
+	if d.version >= 26 {
+		d.order.SmartComboRoutingParams = d.decodeTagValues()
+	}
+}
+
+func (d *orderDecoder) decodeScaleOrderFields() {
+	if d.version >= 20 {
+		d.scanner.readInt() // scale initial level size
+		d.scanner.readInt() // scale subsequent level size
+	} else {
+		d.scanner.readInt() // scale number of components
+		d.scanner.readInt() // scale initial level size
+	}
+
+	scalePriceIncrement := d.scanner.readFloat64()
+
+	if d.version >= 28 && scalePriceIncrement > 0 && scalePriceIncrement != math.MaxFloat64 {
+		d.scanner.readFloat64() // scale price adjust value
+		d.scanner.readInt()     // scale price adjust interval
+		d.scanner.readFloat64() // scale profit offset
+		d.scanner.readBool()    // scale auto reset
+		d.scanner.readInt()     // scale initial position
+		d.scanner.readInt()     // scale initial fill quantity
+		d.scanner.readBool()    // scale random percent
+	}
+}
+
+func (d *orderDecoder) decodeHedgeFields() {
+	if d.version >= 24 {
+		hedgeType := d.scanner.readString()
+		if hedgeType != "" {
+			d.scanner.readString() // hedge param
+		}
+	}
+}
+
+func (d *orderDecoder) decodeClearingFields() {
+	if d.version >= 19 {
+		d.order.ClearingAccount = d.scanner.readString()
+		d.order.ClearingIntent = d.scanner.readString()
+	}
+
+	if d.version >= 22 {
+		d.order.NotHeld = d.scanner.readBool()
+	}
+}
+
+func (d *orderDecoder) decodeDeltaNeutralContract() {
+	if d.version >= 20 && d.scanner.readBool() {
+		d.contract.DeltaNeutralContract.ContractId = d.scanner.readString()
+		d.contract.DeltaNeutralContract.Delta = d.scanner.readFloat64()
+		d.contract.DeltaNeutralContract.Price = d.scanner.readFloat64()
+	}
+}
+
+func (d *orderDecoder) decodeAlgoFields() {
+	if d.version >= 21 {
+		d.order.AlgoStrategy = d.scanner.readString()
+		if d.order.AlgoStrategy != "" {
+			d.order.AlgoParams = d.decodeTagValues()
+		}
+	}
+}
+
+func (d *orderDecoder) decodeMarginAndCommission() {
+	if d.serverVersion >= minServerVerWhatIfExtFields {
+		d.orderState.InitMarginBefore = d.scanner.readFloat64()
+		d.orderState.MaintMarginBefore = d.scanner.readFloat64()
+		d.orderState.EquityWithLoanBefore = d.scanner.readFloat64()
+		d.orderState.InitMarginChange = d.scanner.readFloat64()
+		d.orderState.MaintMarginChange = d.scanner.readFloat64()
+		d.orderState.EquityWithLoanChange = d.scanner.readFloat64()
+	}
+
+	d.orderState.InitMarginAfter = d.scanner.readFloat64()
+	d.orderState.MaintMarginAfter = d.scanner.readFloat64()
+	d.orderState.EquityWithLoanAfter = d.scanner.readFloat64()
+	d.orderState.Commission = d.scanner.readFloat64()
+	d.orderState.MinCommission = d.scanner.readFloat64()
+	d.orderState.MaxCommission = d.scanner.readFloat64()
+	d.orderState.CommissionCurrency = d.scanner.readString()
+	d.orderState.WarningText = d.scanner.readString()
+}
+
+func (d *orderDecoder) decodeRandomizeFlags() {
+	if d.version >= 34 {
+		d.order.RandomizeSize = d.scanner.readBool()
+		d.order.RandomizePrice = d.scanner.readBool()
+	}
+}
+
+// decodeConditions reads the pegged to benchmark fields and the order conditions.
+// It returns false when the remaining fields cannot be decoded.
+func (d *orderDecoder) decodeConditions() bool {
+	if d.serverVersion < minServerVerPeggedToBenchmark {
+		return true
+	}
+
+	if d.order.OrderType == "PEG BENCH" {
+		d.scanner.readInt()     // reference contract id
+		d.scanner.readBool()    // is pegged change amount decrease
+		d.scanner.readFloat64() // pegged change amount
+		d.scanner.readFloat64() // reference change amount
+		d.scanner.readString()  // reference exchange id
+	}
+
+	conditionsCount := d.scanner.readInt()
+	if conditionsCount > 0 {
+		for i := 0; i < conditionsCount; i++ {
+			condition := d.decodeCondition()
+			if condition == nil {
+				return false
+			}
+			d.order.Conditions = append(d.order.Conditions, condition)
+		}
+		d.order.ConditionsIgnoreRth = d.scanner.readBool()
+		d.order.ConditionsCancelOrder = d.scanner.readBool()
+	}
+
+	return true
+}
+
+// decodeCondition reads a single order condition. It returns nil for an unknown condition type, whose fields cannot be skipped.
+func (d *orderDecoder) decodeCondition() Condition {
+	conditionType := ConditionType(d.scanner.readInt())
+	conjunction := ConjunctionAnd
+	if d.scanner.readString() == "o" {
+		conjunction = ConjunctionOr
+	}
+
+	switch conditionType {
+	case PriceConditionType:
+		return PriceCondition{
+			Conjunction:   conjunction,
+			IsMore:        d.scanner.readBool(),
+			Price:         d.scanner.readFloat64(),
+			ContractId:    d.scanner.readInt(),
+			Exchange:      d.scanner.readString(),
+			TriggerMethod: d.scanner.readInt(),
+		}
+	case TimeConditionType:
+		return TimeCondition{
+			Conjunction: conjunction,
+			IsMore:      d.scanner.readBool(),
+			Time:        d.scanner.readString(),
+		}
+	case MarginConditionType:
+		return MarginCondition{
+			Conjunction: conjunction,
+			IsMore:      d.scanner.readBool(),
+			Percent:     d.scanner.readInt(),
+		}
+	case ExecutionConditionType:
+		return ExecutionCondition{
+			Conjunction:  conjunction,
+			SecurityType: d.scanner.readString(),
+			Exchange:     d.scanner.readString(),
+			Symbol:       d.scanner.readString(),
+		}
+	case VolumeConditionType:
+		return VolumeCondition{
+			Conjunction: conjunction,
+			IsMore:      d.scanner.readBool(),
+			Volume:      d.scanner.readInt(),
+			ContractId:  d.scanner.readInt(),
+			Exchange:    d.scanner.readString(),
+		}
+	case PercentChangeConditionType:
+		return PercentChangeCondition{
+			Conjunction:   conjunction,
+			IsMore:        d.scanner.readBool(),
+			ChangePercent: d.scanner.readFloat64(),
+			ContractId:    d.scanner.readInt(),
+			Exchange:      d.scanner.readString(),
+		}
+	}
+
+	log.Printf("unknown condition type %d, skipping remaining fields of order %d", conditionType, d.order.OrderId)
+	return nil
+}
+
+func (d *orderDecoder) decodeAdjustedOrderFields() {
+	if d.serverVersion >= minServerVerPeggedToBenchmark {
+		d.scanner.readString()  // adjusted order type
+		d.scanner.readFloat64() // trigger price
+		d.decodeStopPriceAndLimitPriceOffset()
+		d.scanner.readFloat64() // adjusted stop price
+		d.scanner.readFloat64() // adjusted stop limit price
+		d.scanner.readFloat64() // adjusted trailing amount
+		d.scanner.readInt()     // adjustable trailing unit
+	}
+}
+
+func (d *orderDecoder) decodeStopPriceAndLimitPriceOffset() {
+	d.order.TrailStopPrice = d.scanner.readFloat64()
+	d.scanner.readFloat64() // limit price offset
+}
+
+func (d *orderDecoder) decodeTagValues() []TagValue {
+	count := d.scanner.readInt()
+	if count == 0 {
+		return nil
+	}
+
+	values := make([]TagValue, count)
+	for i := 0; i < count; i++ {
+		values[i] = TagValue{
+			Tag:   d.scanner.readString(),
+			Value: d.scanner.readString(),
+		}
+	}
+
+	return values
+}
+
+// decodeFaGroups converts the XML document of the financial advisor groups into FaGroups
+func decodeFaGroups(data string) ([]FaGroup, error) {
+	document := faGroupsXml{}
+	if err := decodeFaXml(data, &document); err != nil {
+		return nil, err
+	}
+
+	groups := []FaGroup{}
+	for _, group := range document.Groups {
+		groups = append(groups, FaGroup{
+			Name:          group.Name,
+			Accounts:      group.Accounts.Accounts,
+			DefaultMethod: group.DefaultMethod,
+		})
+	}
+
+	return groups, nil
+}
+
+// decodeFaProfiles converts the XML document of the financial advisor profiles into FaProfiles
+func decodeFaProfiles(data string) ([]FaProfile, error) {
+	document := faProfilesXml{}
+	if err := decodeFaXml(data, &document); err != nil {
+		return nil, err
+	}
+
+	profiles := []FaProfile{}
+	for _, profile := range document.Profiles {
+		profiles = append(profiles, FaProfile{
+			Name:        profile.Name,
+			Type:        profile.Type,
+			Allocations: profile.Allocations.Allocations,
+		})
+	}
+
+	return profiles, nil
+}
+
+// decodeFaAliases converts the XML document of the account aliases into FaAliases
+func decodeFaAliases(data string) ([]FaAlias, error) {
+	document := faAliasesXml{}
+	if err := decodeFaXml(data, &document); err != nil {
+		return nil, err
+	}
+
+	if document.Aliases == nil {
+		return []FaAlias{}, nil
+	}
+
+	return document.Aliases, nil
+}
+
+// decodeFaXml reads a financial advisor XML document. The text is read as is, whatever the encoding declared by the document.
+func decodeFaXml(data string, document interface{}) error {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	return decoder.Decode(document)
+}
+
+// decodeHistoricalData converts a HistoricalData incoming message into Bars
+func decodeHistoricalData(serverVersion int, fields []string) ([]Bar, error) {
+	scanner := &parser{fields[1:]}
+
+	if serverVersion < minServerVerSyntRealtimeBars {
+		scanner.readInt() // version
+	}
+	scanner.readInt()    // request id
+	scanner.readString() // start date
+	scanner.readString() // end date
+
+	count := scanner.readInt()
+	bars := make([]Bar, count)
+
+	for i := range bars {
+		date := scanner.readString()
+		barTime, err := parseBarTime(date)
+		if err != nil {
+			return nil, err
+		}
+
+		bars[i] = Bar{
+			Time:   barTime,
+			Open:   scanner.readFloat64(),
+			High:   scanner.readFloat64(),
+			Low:    scanner.readFloat64(),
+			Close:  scanner.readFloat64(),
+			Volume: int64(scanner.readFloat64()),
+			WAP:    scanner.readFloat64(),
+		}
+		if serverVersion < minServerVerSyntRealtimeBars {
+			scanner.readString() // has gaps
+		}
+		bars[i].Count = scanner.readInt()
+	}
+
+	return bars, nil
+}
+
+// decodeHistoricalDataUpdate converts a HistoricalDataUpdate incoming message into a Bar
+func decodeHistoricalDataUpdate(fields []string) (Bar, error) {
+	scanner := &parser{fields[2:]}
+
+	count := scanner.readInt()
+	barTime, err := parseBarTime(scanner.readString())
+	if err != nil {
+		return Bar{}, err
+	}
+
+	bar := Bar{Time: barTime, Count: count}
+	bar.Open = scanner.readFloat64()
+	bar.Close = scanner.readFloat64()
+	bar.High = scanner.readFloat64()
+	bar.Low = scanner.readFloat64()
+	bar.WAP = scanner.readFloat64()
+	bar.Volume = int64(scanner.readFloat64())
+
+	return bar, nil
+}
+
+// decodeHistoricalTicks converts a HistoricalTicks incoming message into midpoints. It reports whether the request is complete.
+func decodeHistoricalTicks(fields []string) ([]Midpoint, bool) {
+	scanner := &parser{fields[2:]}
+
+	count := scanner.readInt()
+	midpoints := make([]Midpoint, 0, count)
+	for i := 0; i < count; i++ {
+		timestamp := scanner.readInt64()
+		scanner.readInt() // unused
+		price := scanner.readFloat64()
+		scanner.readString() // size, always 0
+
+		midpoints = append(midpoints, Midpoint{Time: time.Unix(timestamp, 0), Price: price})
+	}
+
+	return midpoints, scanner.readBool()
+}
+
+// decodeHistoricalTicksBidAsk converts a HistoricalTicksBidAsk incoming message into spreads. It reports whether the request is complete.
+func decodeHistoricalTicksBidAsk(fields []string) ([]BidAsk, bool) {
+	scanner := &parser{fields[2:]}
+
+	count := scanner.readInt()
+	spreads := make([]BidAsk, 0, count)
+	for i := 0; i < count; i++ {
+		timestamp := scanner.readInt64()
+
+		// unlike tick-by-tick data, bit 0 is ask past high and bit 1 is bid past low
+		mask := scanner.readInt()
+		attribute := BidAskAttribute{
+			AskPastHigh: mask&0x1 == 0x1,
+			BidPastLow:  mask&0x2 == 0x2,
+		}
+
+		spreads = append(spreads, BidAsk{
+			Time:            time.Unix(timestamp, 0),
+			BidPrice:        scanner.readFloat64(),
+			AskPrice:        scanner.readFloat64(),
+			BidSize:         int64(scanner.readFloat64()),
+			AskSize:         int64(scanner.readFloat64()),
+			BidAskAttribute: attribute,
+		})
+	}
+
+	return spreads, scanner.readBool()
+}
+
+// decodeHistoricalTicksLast converts a HistoricalTicksLast incoming message into trades. It reports whether the request is complete.
+func decodeHistoricalTicksLast(fields []string) ([]Trade, bool) {
+	scanner := &parser{fields[2:]}
+
+	count := scanner.readInt()
+	trades := make([]Trade, 0, count)
+	for i := 0; i < count; i++ {
+		timestamp := scanner.readInt64()
+
+		mask := scanner.readInt()
+		attribute := TradeAttribute{
+			PastLimit:  mask&0x1 == 0x1,
+			Unreported: mask&0x2 == 0x2,
+		}
+
+		trades = append(trades, Trade{
+			TickType:          "Last",
+			Time:              time.Unix(timestamp, 0),
+			Price:             scanner.readFloat64(),
+			Size:              int64(scanner.readFloat64()),
+			TradeAttribute:    attribute,
+			Exchange:          scanner.readString(),
+			SpecialConditions: scanner.readString(),
+		})
+	}
+
+	return trades, scanner.readBool()
+}
+
+// parseBarTime reads the date of a historical bar. Depending on the format date of the request and the bar size, it is
+//   - seconds since the epoch, e.g. 1654867800
+//   - a date, e.g. 20220610, for daily and longer bars
+//   - a date and time with an optional time zone, e.g. 20220610 09:30:00 US/Eastern
+//
+// Dates and times without time zone are in the time zone of TWS, read in the local time zone.
+func parseBarTime(value string) (time.Time, error) {
+	if len(value) == 8 {
+		return time.ParseInLocation("20060102", value, time.Local)
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	parts := strings.Fields(value)
+	if len(parts) < 2 || len(parts) > 3 {
+		return time.Time{}, fmt.Errorf("invalid bar date %q", value)
+	}
+
+	location := time.Local
+	if len(parts) == 3 {
+		var err error
+		if location, err = time.LoadLocation(parts[2]); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone of bar date %q: %w", value, err)
+		}
+	}
+
+	return time.ParseInLocation("20060102 15:04:05", parts[0]+" "+parts[1], location)
+}
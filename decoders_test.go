@@ -2,6 +2,8 @@ package ibapi
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,3 +54,597 @@ func TestDecodeRealTimeBars(t *testing.T) {
 	assert.Equal(t, wap, bar.WAP)
 	assert.Equal(t, count, bar.Count)
 }
+
+func TestDecodeOrderStatus(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", orderStatus),
+		"13", "Filled", "2", "0", "4100.25", "1376327563", "0", "4100.25", "100", "", "0",
+	}
+
+	status := decodeOrderStatus(maxClientVer, packet)
+
+	assert.Equal(t, 13, status.OrderId)
+	assert.Equal(t, "Filled", status.Status)
+	assert.Equal(t, 2.0, status.Filled)
+	assert.Equal(t, 0.0, status.Remaining)
+	assert.Equal(t, 4100.25, status.AverageFillPrice)
+	assert.Equal(t, 1376327563, status.PermId)
+	assert.Equal(t, 4100.25, status.LastFillPrice)
+	assert.Equal(t, 100, status.ClientId)
+}
+
+func TestDecodeOpenOrder(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", openOrder),
+		"13",
+		// contract
+		"495512551", "ES", "FUT", "20220916", "0", "?", "50", "GLOBEX", "USD", "ESU2", "ES",
+		// main order fields
+		"BUY", "2", "LMT", "4100.25", "0.0", "DAY", "", "DU1234", "", "0", "",
+		// client id, perm id
+		"100", "1376327563",
+		// outside rth, hidden, discretionary amount, good after time, shares allocation
+		"1", "0", "0", "", "",
+		// financial advisor, model code
+		"", "", "", "", "",
+		// good till date, rule 80A, percent offset, settling firm
+		"", "", "", "",
+		// short sale
+		"0", "", "-1",
+		// auction strategy, box orders, peg to stock
+		"0", "", "", "", "", "",
+		// display size, block order, sweep to fill, all or none, min quantity, oca type
+		"0", "0", "0", "0", "", "3",
+		// deprecated fields, parent id, trigger method
+		"0", "0", "", "0", "0",
+		// volatility orders
+		"", "0", "", "", "0", "",
+		// trail stop price, trailing percent, basis points
+		"", "", "", "",
+		// combo legs, order combo legs, smart combo routing
+		"", "0", "0", "0",
+		// scale orders
+		"", "", "",
+		// hedge type, opt out smart routing, clearing account, clearing intent, not held
+		"", "0", "", "IB", "0",
+		// delta neutral contract
+		"0",
+		// algo strategy
+		"Adaptive", "1", "adaptivePriority", "Normal",
+		// solicited, what if, status
+		"0", "0", "PreSubmitted",
+		// margin
+		"1.7976931348623157E308", "1.7976931348623157E308", "1.7976931348623157E308",
+		"1.7976931348623157E308", "1.7976931348623157E308", "1.7976931348623157E308",
+		"1.7976931348623157E308", "1.7976931348623157E308", "1.7976931348623157E308",
+		// commission
+		"", "", "", "", "",
+		// randomize size/price, conditions
+		"0", "0", "0",
+		// adjusted order fields
+		"None", "", "", "", "", "", "", "0",
+		// soft dollar tier, cash quantity
+		"", "", "", "0",
+		// auto price for hedge, oms container, discretionary up to limit, price management algo
+		"0", "0", "0", "0",
+		// duration, post to ats, auto cancel parent
+		"", "", "0",
+	}
+
+	order := decodeOpenOrder(maxClientVer, packet)
+
+	assert.Equal(t, 13, order.Order.OrderId)
+	assert.Equal(t, 495512551, order.Contract.ContractId)
+	assert.Equal(t, "ESU2", order.Contract.LocalSymbol)
+	assert.Equal(t, "BUY", order.Order.Action)
+	assert.Equal(t, 2.0, order.Order.TotalQuantity)
+	assert.Equal(t, "LMT", order.Order.OrderType)
+	assert.Equal(t, 4100.25, order.Order.LimitPrice)
+	assert.Equal(t, "DU1234", order.Order.Account)
+	assert.Equal(t, 100, order.Order.ClientId)
+	assert.Equal(t, 1376327563, order.Order.PermId)
+	assert.True(t, order.Order.OutsideRth)
+	assert.Equal(t, 3, order.Order.OcaType)
+	assert.Equal(t, "IB", order.Order.ClearingIntent)
+	assert.Equal(t, "Adaptive", order.Order.AlgoStrategy)
+	assert.Equal(t, []TagValue{{Tag: "adaptivePriority", Value: "Normal"}}, order.Order.AlgoParams)
+	assert.Equal(t, "PreSubmitted", order.OrderState.Status)
+}
+
+func TestDecodeConditions(t *testing.T) {
+	conditions := []Condition{
+		PriceCondition{Conjunction: ConjunctionOr, IsMore: true, Price: 4100.25, ContractId: 495512551, Exchange: "GLOBEX", TriggerMethod: 2},
+		TimeCondition{IsMore: true, Time: "20220610 09:30:00 US/Eastern"},
+		MarginCondition{Percent: 30},
+		ExecutionCondition{SecurityType: "STK", Exchange: "SMART", Symbol: "AAPL"},
+		VolumeCondition{IsMore: true, Volume: 100000, ContractId: 265598, Exchange: "SMART"},
+		PercentChangeCondition{ChangePercent: -2.5, ContractId: 265598, Exchange: "SMART"},
+	}
+
+	message := messageBuilder{}
+	message.addInt(len(conditions))
+	for _, condition := range conditions {
+		encodeCondition(&message, condition)
+	}
+	message.addBool(true) // conditions ignore rth
+	message.addBool(true) // conditions cancel order
+
+	fields := strings.Split(strings.TrimSuffix(message.Encode(), "\x00"), "\x00")
+	decoder := orderDecoder{scanner: &parser{fields}, serverVersion: maxClientVer}
+
+	assert.True(t, decoder.decodeConditions())
+	assert.Equal(t, conditions, decoder.order.Conditions)
+	assert.True(t, decoder.order.ConditionsIgnoreRth)
+	assert.True(t, decoder.order.ConditionsCancelOrder)
+
+	t.Run("unknown condition type", func(t *testing.T) {
+		decoder := orderDecoder{scanner: &parser{[]string{"1", "2", "a", "1"}}, serverVersion: maxClientVer}
+
+		assert.False(t, decoder.decodeConditions())
+	})
+}
+
+func TestDecodeExecution(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", executionData),
+		"9001", "13",
+		// contract
+		"495512551", "ES", "FUT", "20220916", "0", "", "50", "GLOBEX", "USD", "ESU2", "ES",
+		// execution
+		"0000e0d5.62a2a8a4.01.01", "20220610  10:15:01", "DU1234", "GLOBEX", "BOT", "2", "4100.25", "1376327563", "100", "0",
+		"2", "4100.25", "ref", "", "", "", "2",
+	}
+
+	execution := decodeExecution(maxClientVer, packet)
+
+	assert.Equal(t, 13, execution.OrderId)
+	assert.Equal(t, "ESU2", execution.Contract.LocalSymbol)
+	assert.Equal(t, "ES", execution.Contract.TradingClass)
+	assert.Equal(t, "0000e0d5.62a2a8a4.01.01", execution.ExecId)
+	assert.Equal(t, "DU1234", execution.Account)
+	assert.Equal(t, "BOT", execution.Side)
+	assert.Equal(t, 2.0, execution.Shares)
+	assert.Equal(t, 4100.25, execution.Price)
+	assert.Equal(t, 1376327563, execution.PermId)
+	assert.Equal(t, 2.0, execution.CumulativeQuantity)
+	assert.Equal(t, "ref", execution.OrderRef)
+	assert.Equal(t, 2, execution.LastLiquidity)
+}
+
+func TestDecodeCommissionReport(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", commissionReport),
+		"1", "0000e0d5.62a2a8a4.01.01", "4.24", "USD", "1.7976931348623157E308", "1.7976931348623157E308", "",
+	}
+
+	report := decodeCommissionReport(packet)
+
+	assert.Equal(t, "0000e0d5.62a2a8a4.01.01", report.ExecId)
+	assert.Equal(t, 4.24, report.Commission)
+	assert.Equal(t, "USD", report.Currency)
+	assert.Equal(t, 0, report.YieldRedemptionDate)
+}
+
+func TestDecodeCompletedOrder(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", completedOrder),
+		// contract
+		"265598", "AAPL", "STK", "", "0", "?", "", "SMART", "USD", "AAPL", "NMS",
+		// main order fields
+		"SELL", "100", "MKT", "0.0", "0.0", "DAY", "", "DU1234", "", "0", "",
+		// perm id
+		"1376327563",
+		// outside rth, hidden, discretionary amount, good after time
+		"0", "0", "0", "",
+		// financial advisor, model code
+		"", "", "", "", "",
+		// good till date, rule 80A, percent offset, settling firm
+		"", "", "", "",
+		// short sale
+		"0", "", "-1",
+		// box orders, peg to stock
+		"", "", "", "", "",
+		// display size, sweep to fill, all or none, min quantity, oca type, trigger method
+		"2147483647", "0", "0", "", "3", "0",
+		// volatility orders
+		"", "0", "", "", "0", "",
+		// trail stop price, trailing percent
+		"", "",
+		// combo legs, order combo legs, smart combo routing
+		"", "0", "0", "0",
+		// scale orders
+		"", "", "",
+		// hedge type, clearing account, clearing intent, not held
+		"", "", "IB", "0",
+		// delta neutral contract, algo strategy, solicited
+		"0", "", "0",
+		// status
+		"Filled",
+		// randomize size/price, conditions
+		"0", "0", "0",
+		// trail stop price, limit price offset, cash quantity
+		"", "", "0",
+		// auto price for hedge, oms container
+		"0", "0",
+		// auto cancel date, filled quantity, reference futures contract id, auto cancel parent
+		"", "100", "0", "0",
+		// shareholder, imbalance only, route marketable to bbo, parent perm id
+		"Not an insider or substantial shareholder", "0", "0", "0",
+		// completed time, completed status
+		"20220610 10:15:01 America/New_York", "Filled Size: 100",
+	}
+
+	order := decodeCompletedOrder(maxClientVer, packet)
+
+	assert.Equal(t, 265598, order.Contract.ContractId)
+	assert.Equal(t, "NMS", order.Contract.TradingClass)
+	assert.Equal(t, "SELL", order.Order.Action)
+	assert.Equal(t, 100.0, order.Order.TotalQuantity)
+	assert.Equal(t, "MKT", order.Order.OrderType)
+	assert.Equal(t, 1376327563, order.Order.PermId)
+	assert.Equal(t, 100.0, order.Order.FilledQuantity)
+	assert.Equal(t, "Filled", order.OrderState.Status)
+	assert.Equal(t, "20220610 10:15:01 America/New_York", order.OrderState.CompletedTime)
+	assert.Equal(t, "Filled Size: 100", order.OrderState.CompletedStatus)
+}
+
+func TestDecodeAccountSummary(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", accountSummary),
+		"1", "9001", "DU1234", "NetLiquidation", "1012345.67", "USD",
+	}
+
+	value := decodeAccountSummary(packet)
+
+	assert.Equal(t, AccountSummaryValue{Account: "DU1234", Tag: AccountSummaryNetLiquidation, Value: "1012345.67", Currency: "USD"}, value)
+}
+
+func TestDecodePosition(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", positionData),
+		"3", "DU1234", "265598", "AAPL", "STK", "", "0.0", "", "", "NASDAQ", "USD", "AAPL", "NMS", "10.5", "150.25",
+	}
+
+	position := decodePosition(packet)
+
+	assert.Equal(t, "DU1234", position.Account)
+	assert.Equal(t, 265598, position.Contract.ContractId)
+	assert.Equal(t, "AAPL", position.Contract.Symbol)
+	assert.Equal(t, "NMS", position.Contract.TradingClass)
+	assert.Equal(t, 10.5, position.Quantity)
+	assert.Equal(t, 150.25, position.AvgCost)
+}
+
+func TestDecodePositionMulti(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", positionMulti),
+		"1", "9001", "DU1234", "265598", "AAPL", "STK", "", "0.0", "", "", "NASDAQ", "USD", "AAPL", "NMS", "100", "150.25", "Growth",
+	}
+
+	position := decodePositionMulti(packet)
+
+	assert.Equal(t, "DU1234", position.Account)
+	assert.Equal(t, 265598, position.Contract.ContractId)
+	assert.Equal(t, 100.0, position.Quantity)
+	assert.Equal(t, 150.25, position.AvgCost)
+	assert.Equal(t, "Growth", position.ModelCode)
+}
+
+func TestDecodeAccountUpdateMulti(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", accountUpdateMulti),
+		"1", "9001", "DU1234", "Growth", "NetLiquidationByCurrency", "250000.5", "USD",
+	}
+
+	value := decodeAccountUpdateMulti(packet)
+
+	assert.Equal(t, AccountValue{Account: "DU1234", ModelCode: "Growth", Key: "NetLiquidationByCurrency", Value: "250000.5", Currency: "USD"}, value)
+}
+
+func TestDecodeAccountValue(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", accountValue),
+		"2", "NetLiquidation", "1012345.67", "USD", "DU1234",
+	}
+
+	value := decodeAccountValue(packet)
+
+	assert.Equal(t, AccountValue{Account: "DU1234", Key: "NetLiquidation", Value: "1012345.67", Currency: "USD"}, value)
+}
+
+func TestDecodePortfolioValue(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", portfolioValue),
+		"8", "265598", "AAPL", "STK", "", "0.0", "", "", "NASDAQ", "USD", "AAPL", "NMS",
+		"100", "151.5", "15150", "150.25", "125", "-10.5", "DU1234",
+	}
+
+	item := decodePortfolioValue(packet)
+
+	assert.Equal(t, "DU1234", item.Account)
+	assert.Equal(t, 265598, item.Contract.ContractId)
+	assert.Equal(t, "NASDAQ", item.Contract.PrimaryExchange)
+	assert.Equal(t, "NMS", item.Contract.TradingClass)
+	assert.Equal(t, 100.0, item.Position)
+	assert.Equal(t, 151.5, item.MarketPrice)
+	assert.Equal(t, 15150.0, item.MarketValue)
+	assert.Equal(t, 150.25, item.AverageCost)
+	assert.Equal(t, 125.0, item.UnrealizedPnL)
+	assert.Equal(t, -10.5, item.RealizedPnL)
+}
+
+func TestDecodeAccountUpdate(t *testing.T) {
+	update, ok := decodeAccountUpdate([]string{fmt.Sprintf("%d", accountValue), "2", "NetLiquidation", "1012345.67", "USD", "DU1234"})
+	assert.True(t, ok)
+	assert.Equal(t, AccountUpdate{AccountValue: &AccountValue{Account: "DU1234", Key: "NetLiquidation", Value: "1012345.67", Currency: "USD"}}, update)
+
+	update, ok = decodeAccountUpdate([]string{fmt.Sprintf("%d", accountUpdateTime), "1", "15:30"})
+	assert.True(t, ok)
+	assert.Equal(t, AccountUpdate{UpdateTime: "15:30"}, update)
+
+	update, ok = decodeAccountUpdate([]string{fmt.Sprintf("%d", accountDownloadEnd), "1", "DU1234"})
+	assert.True(t, ok)
+	assert.Equal(t, AccountUpdate{DownloadEnd: true}, update)
+
+	_, ok = decodeAccountUpdate([]string{fmt.Sprintf("%d", positionData), "3"})
+	assert.False(t, ok)
+}
+
+func TestDecodePnL(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", pnl), "9001", "-125.5", "100.25", "-225.75"}
+
+	assert.Equal(t, PnL{DailyPnL: -125.5, UnrealizedPnL: 100.25, RealizedPnL: -225.75}, decodePnL(minServerVerRealizedPnl, packet))
+	assert.Equal(t, PnL{DailyPnL: -125.5}, decodePnL(minServerVerPnl, packet))
+}
+
+func TestDecodePnLSingle(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", pnlSingle), "9002", "100", "-125.5", "100.25", "-225.75", "15150"}
+
+	assert.Equal(t, PnLSingle{Position: 100, DailyPnL: -125.5, UnrealizedPnL: 100.25, RealizedPnL: -225.75, Value: 15150}, decodePnLSingle(minServerVerRealizedPnl, packet))
+
+	packet = []string{fmt.Sprintf("%d", pnlSingle), "9002", "100", "-125.5", "100.25", "15150"}
+
+	assert.Equal(t, PnLSingle{Position: 100, DailyPnL: -125.5, UnrealizedPnL: 100.25, Value: 15150}, decodePnLSingle(minServerVerUnrealizedPnl, packet))
+}
+
+func TestDecodeFaProfiles(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-16"?>
+<ListOfAllocationProfiles>
+	<AllocationProfile>
+		<name>Percent_60_40</name>
+		<type>1</type>
+		<ListOfAllocations varName="listOfAllocations">
+			<Allocation>
+				<acct>DU119915</acct>
+				<amount>60.0</amount>
+			</Allocation>
+			<Allocation>
+				<acct>DU119916</acct>
+				<amount>40.0</amount>
+			</Allocation>
+		</ListOfAllocations>
+	</AllocationProfile>
+</ListOfAllocationProfiles>`
+
+	profiles, err := decodeFaProfiles(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []FaProfile{
+		{Name: "Percent_60_40", Type: 1, Allocations: []FaAllocation{{Account: "DU119915", Amount: 60}, {Account: "DU119916", Amount: 40}}},
+	}, profiles)
+}
+
+func TestDecodeFaAliases(t *testing.T) {
+	data := `<ListOfAccountAliases><AccountAlias><account>DU119915</account><alias>Growth</alias></AccountAlias></ListOfAccountAliases>`
+
+	aliases, err := decodeFaAliases(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []FaAlias{{Account: "DU119915", Alias: "Growth"}}, aliases)
+}
+
+func TestDecodeTickPrice(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", tickPrice), "6", "9001", "1", "151.25", "300", "5"}
+
+	tick := decodeTickPrice(maxClientVer, packet)
+
+	assert.Equal(t, TickBid, tick.Type())
+	assert.Equal(t, 151.25, tick.Price)
+	assert.Equal(t, 300.0, tick.Size)
+	assert.Equal(t, TickAttributes{CanAutoExecute: true, PreOpen: true}, tick.Attributes)
+	assert.Equal(t, "Bid", tick.Type().String())
+}
+
+func TestDecodeTickSize(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", tickSize), "6", "9001", "8", "1234567.5"}
+
+	assert.Equal(t, SizeTick{TickType: TickVolume, Size: 1234567.5}, decodeTickSize(packet))
+}
+
+func TestDecodeTickOptionComputation(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", tickOptionComputation),
+		"9001", "13", "1", "0.25", "-2", "5.1", "0.12", "0.03", "0.2", "-0.05", "-1",
+	}
+
+	tick := decodeTickOptionComputation(maxClientVer, packet)
+
+	assert.Equal(t, TickModelOptionComputation, tick.Type())
+	assert.True(t, tick.PriceBased)
+	assert.Equal(t, 0.25, tick.ImpliedVolatility)
+	assert.Equal(t, math.MaxFloat64, tick.Delta)
+	assert.Equal(t, 5.1, tick.OptionPrice)
+	assert.Equal(t, -0.05, tick.Theta)
+	assert.Equal(t, math.MaxFloat64, tick.UnderlyingPrice)
+}
+
+func TestDecodeMarketDataType(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", marketDataType), "1", "9001", "3"}
+
+	tick := decodeMarketDataType(packet)
+
+	assert.Equal(t, MarketDataTypeTick{MarketDataType: MarketDataDelayed}, tick)
+	assert.Equal(t, "MarketDataType", tick.Type().String())
+}
+
+func TestDecodeMarketDepthL2(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", marketDepthL2), "1", "9001", "2", "ARCA", "1", "1", "151.25", "300", "1"}
+
+	event := decodeMarketDepthL2(maxClientVer, packet)
+
+	assert.Equal(t, DepthEvent{Position: 2, MarketMaker: "ARCA", Operation: DepthUpdate, Side: DepthBid, Price: 151.25, Size: 300, SmartDepth: true}, event)
+}
+
+func TestDecodeMarketDepthExchanges(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", marketDepthExchanges),
+		"2",
+		"ARCA", "STK", "NYSE", "Deep2", "1",
+		"GLOBEX", "FUT", "", "Deep", "2",
+	}
+
+	exchanges := decodeMarketDepthExchanges(maxClientVer, packet)
+
+	assert.Equal(t, []DepthExchange{
+		{Exchange: "ARCA", SecurityType: "STK", ListingExchange: "NYSE", ServiceDataType: "Deep2", AggregatedGroup: 1},
+		{Exchange: "GLOBEX", SecurityType: "FUT", ServiceDataType: "Deep", AggregatedGroup: 2},
+	}, exchanges)
+}
+
+func TestDecodeSmartComponents(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", smartComponents), "9001", "2", "1", "ARCA", "P", "2", "NASDAQ", "Q"}
+
+	components := decodeSmartComponents(packet)
+
+	assert.Equal(t, []SmartComponent{
+		{BitNumber: 1, Exchange: "ARCA", ExchangeLetter: "P"},
+		{BitNumber: 2, Exchange: "NASDAQ", ExchangeLetter: "Q"},
+	}, components)
+}
+
+func TestDecodeTickRequestParameters(t *testing.T) {
+	packet := []string{fmt.Sprintf("%d", tickRequestParameters), "9001", "0.01", "9c0001", "3"}
+
+	assert.Equal(t, RequestParametersTick{MinTick: 0.01, BboExchange: "9c0001", SnapshotPermissions: 3}, decodeTickRequestParameters(packet))
+}
+
+func TestDecodeHistoricalData(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", historicalData),
+		"9001", "20220603 20:00:00", "20220610 20:00:00", "2",
+		"1654867800", "151.25", "152.5", "150.75", "152", "12345", "151.6", "100",
+		"1654871400", "152", "153", "151.5", "152.75", "23456.5", "152.25", "200",
+	}
+
+	bars, err := decodeHistoricalData(maxClientVer, packet)
+
+	assert.Nil(t, err)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, int64(1654867800), bars[0].Time.Unix())
+	assert.Equal(t, 151.25, bars[0].Open)
+	assert.Equal(t, 152.5, bars[0].High)
+	assert.Equal(t, 150.75, bars[0].Low)
+	assert.Equal(t, 152.0, bars[0].Close)
+	assert.Equal(t, int64(12345), bars[0].Volume)
+	assert.Equal(t, 151.6, bars[0].WAP)
+	assert.Equal(t, 100, bars[0].Count)
+	assert.Equal(t, int64(23456), bars[1].Volume)
+}
+
+func TestDecodeHistoricalDataUpdate(t *testing.T) {
+	packet := []string{
+		fmt.Sprintf("%d", historicalDataUpdate),
+		"9001", "42", "1654871400", "152", "152.5", "153", "151.5", "152.25", "3456",
+	}
+
+	bar, err := decodeHistoricalDataUpdate(packet)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1654871400), bar.Time.Unix())
+	assert.Equal(t, 152.0, bar.Open)
+	assert.Equal(t, 152.5, bar.Close)
+	assert.Equal(t, 153.0, bar.High)
+	assert.Equal(t, 151.5, bar.Low)
+	assert.Equal(t, 152.25, bar.WAP)
+	assert.Equal(t, int64(3456), bar.Volume)
+	assert.Equal(t, 42, bar.Count)
+}
+
+func TestDecodeHistoricalTicks(t *testing.T) {
+	midpoints, done := decodeHistoricalTicks([]string{
+		fmt.Sprintf("%d", historicalTicks),
+		"9001", "2",
+		"1654867800", "0", "151.25", "0",
+		"1654867801", "0", "151.3", "0",
+		"0",
+	})
+
+	assert.False(t, done)
+	assert.Equal(t, []Midpoint{{Time: time.Unix(1654867800, 0), Price: 151.25}, {Time: time.Unix(1654867801, 0), Price: 151.3}}, midpoints)
+
+	spreads, done := decodeHistoricalTicksBidAsk([]string{
+		fmt.Sprintf("%d", historicalTicksBidAsk),
+		"9001", "1",
+		"1654867800", "2", "151.2", "151.3", "300", "400",
+		"1",
+	})
+
+	assert.True(t, done)
+	assert.Equal(t, BidAsk{
+		Time:            time.Unix(1654867800, 0),
+		BidPrice:        151.2,
+		AskPrice:        151.3,
+		BidSize:         300,
+		AskSize:         400,
+		BidAskAttribute: BidAskAttribute{BidPastLow: true},
+	}, spreads[0])
+
+	trades, done := decodeHistoricalTicksLast([]string{
+		fmt.Sprintf("%d", historicalTicksLast),
+		"9001", "1",
+		"1654867800", "2", "151.25", "100", "ARCA", "T",
+		"1",
+	})
+
+	assert.True(t, done)
+	assert.Equal(t, Trade{
+		TickType:          "Last",
+		Time:              time.Unix(1654867800, 0),
+		Price:             151.25,
+		Size:              100,
+		TradeAttribute:    TradeAttribute{Unreported: true},
+		Exchange:          "ARCA",
+		SpecialConditions: "T",
+	}, trades[0])
+
+	t.Run("with decimal sizes", func(t *testing.T) {
+		trades, _ := decodeHistoricalTicksLast([]string{
+			fmt.Sprintf("%d", historicalTicksLast),
+			"9001", "1",
+			"1654867800", "0", "29500.5", "12.5", "PAXOS", "",
+			"1",
+		})
+
+		assert.Equal(t, int64(12), trades[0].Size)
+	})
+}
+
+func TestParseBarTime(t *testing.T) {
+	barTime, err := parseBarTime("20220610")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 10, 0, 0, 0, 0, time.Local), barTime)
+
+	barTime, err = parseBarTime("20220610  09:30:00")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 10, 9, 30, 0, 0, time.Local), barTime)
+
+	barTime, err = parseBarTime("20220610 09:30:00 UTC")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 10, 9, 30, 0, 0, time.UTC), barTime)
+
+	_, err = parseBarTime("20220610 09:30:00 Nowhere/Land")
+	assert.NotNil(t, err)
+
+	_, err = parseBarTime("June 10")
+	assert.NotNil(t, err)
+}
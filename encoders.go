@@ -1,5 +1,11 @@
 package ibapi
 
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
 type realTimeBarsEncoder struct {
 	serverVersion int
 	version       int
@@ -112,3 +118,818 @@ func (e *contractDetailsEncoder) encode() string {
 
 	return message.Encode()
 }
+
+type placeOrderEncoder struct {
+	serverVersion int
+	version       int
+	orderId       int
+
+	contract Contract
+	order    Order
+}
+
+func (e *placeOrderEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(placeOrder)
+	if e.serverVersion < minServerVerOrderContainer {
+		message.addInt(e.version)
+	}
+	message.addInt(e.orderId)
+
+	// contract fields
+
+	if e.serverVersion >= minServerVerPlaceOrderConid {
+		message.addInt(e.contract.ContractId)
+	}
+	message.addString(e.contract.Symbol)
+	message.addString(e.contract.SecurityType)
+	message.addString(e.contract.LastTradeDateOrContractMonth)
+	message.addFloat64(e.contract.Strike)
+	message.addString(e.contract.Right)
+	message.addString(e.contract.Multiplier)
+	message.addString(e.contract.Exchange)
+	message.addString(e.contract.PrimaryExchange)
+	message.addString(e.contract.Currency)
+	message.addString(e.contract.LocalSymbol)
+	if e.serverVersion >= minServerVersionTradingClass {
+		message.addString(e.contract.TradingClass)
+	}
+	if e.serverVersion >= minServerVersionSecurityIdType {
+		message.addString(e.contract.SecurityIdType)
+		message.addString(e.contract.SecurityId)
+	}
+
+	// main order fields
+
+	message.addString(e.order.Action)
+	if e.serverVersion >= minServerVerFractionalPositions {
+		message.addDecimal(e.order.TotalQuantity)
+	} else {
+		message.addInt(int(e.order.TotalQuantity))
+	}
+	message.addString(e.order.OrderType)
+	if e.serverVersion < minServerVerOrderComboLegsPrice {
+		message.addFloat64(e.order.LimitPrice)
+	} else {
+		message.addOptionalFloat64(e.order.LimitPrice)
+	}
+	if e.serverVersion < minServerVerTrailingPercent {
+		message.addFloat64(e.order.AuxPrice)
+	} else {
+		message.addOptionalFloat64(e.order.AuxPrice)
+	}
+
+	// extended order fields
+
+	message.addString(e.order.TimeInForce)
+	message.addString(e.order.OcaGroup)
+	message.addString(e.order.Account)
+	message.addString(e.order.OpenClose)
+	message.addInt(e.order.Origin)
+	message.addString(e.order.OrderRef)
+	message.addBool(e.order.Transmit)
+	message.addInt(e.order.ParentId)
+	message.addBool(e.order.BlockOrder)
+	message.addBool(e.order.SweepToFill)
+	message.addInt(e.order.DisplaySize)
+	message.addInt(e.order.TriggerMethod)
+	message.addBool(e.order.OutsideRth)
+	message.addBool(e.order.Hidden)
+
+	// combo legs for BAG requests
+
+	if e.contract.SecurityType == "BAG" {
+		message.addInt(len(e.contract.ComboLegs))
+		for _, leg := range e.contract.ComboLegs {
+			message.addInt(leg.ContractId)
+			message.addInt(leg.Ratio)
+			message.addString(leg.Action)
+			message.addString(leg.Exchange)
+			message.addInt(leg.OpenClose)
+			message.addInt(leg.ShortSaleSlot)
+			message.addString(leg.DesignatedLocation)
+			if e.serverVersion >= minServerVerSshortxOld {
+				message.addInt(leg.ExemptCode)
+			}
+		}
+
+		if e.serverVersion >= minServerVerOrderComboLegsPrice {
+			message.addInt(len(e.order.OrderComboLegs))
+			for _, leg := range e.order.OrderComboLegs {
+				message.addOptionalFloat64(leg.Price)
+			}
+		}
+
+		if e.serverVersion >= minServerVerSmartComboRoutingParams {
+			message.addInt(len(e.order.SmartComboRoutingParams))
+			for _, param := range e.order.SmartComboRoutingParams {
+				message.addString(param.Tag)
+				message.addString(param.Value)
+			}
+		}
+	}
+
+	message.addString("") // deprecated shares allocation
+	message.addFloat64(e.order.DiscretionaryAmount)
+	message.addString(e.order.GoodAfterTime)
+	message.addString(e.order.GoodTillDate)
+
+	message.addString(e.order.FaGroup)
+	message.addString(e.order.FaMethod)
+	message.addString(e.order.FaPercentage)
+	message.addString(e.order.FaProfile)
+	if e.serverVersion >= minServerVerModelsSupport {
+		message.addString(e.order.ModelCode)
+	}
+
+	// institutional short sale slot data
+
+	message.addInt(e.order.ShortSaleSlot)
+	message.addString(e.order.DesignatedLocation)
+	if e.serverVersion >= minServerVerSshortxOld {
+		message.addInt(e.order.ExemptCode)
+	}
+
+	message.addInt(e.order.OcaType)
+	message.addString(e.order.Rule80A)
+	message.addString(e.order.SettlingFirm)
+	message.addBool(e.order.AllOrNone)
+	message.addOptionalInt(e.order.MinQuantity)
+	message.addOptionalFloat64(e.order.PercentOffset)
+	message.addBool(false) // deprecated eTradeOnly
+	message.addBool(false) // deprecated firmQuoteOnly
+	message.addString("")  // deprecated nbboPriceCap
+	message.addInt(0)      // auction strategy
+	message.addString("")  // starting price
+	message.addString("")  // stock reference price
+	message.addString("")  // delta
+	message.addString("")  // stock range lower
+	message.addString("")  // stock range upper
+	message.addBool(e.order.OverridePercentageConstraints)
+
+	// volatility orders
+
+	message.addOptionalFloat64(e.order.Volatility)
+	message.addOptionalInt(e.order.VolatilityType)
+	message.addString("") // delta neutral order type
+	message.addString("") // delta neutral aux price
+
+	message.addBool(false) // continuous update
+	message.addString("")  // reference price type
+	message.addOptionalFloat64(e.order.TrailStopPrice)
+	if e.serverVersion >= minServerVerTrailingPercent {
+		message.addOptionalFloat64(e.order.TrailingPercent)
+	}
+
+	// scale orders
+
+	if e.serverVersion >= minServerVerScaleOrders2 {
+		message.addString("") // scale initial level size
+		message.addString("") // scale subsequent level size
+	} else {
+		message.addString("") // scale number of components
+		message.addString("") // scale component size
+	}
+	message.addString("") // scale price increment
+
+	if e.serverVersion >= minServerVerScaleTable {
+		message.addString("") // scale table
+		message.addString("") // active start time
+		message.addString("") // active stop time
+	}
+
+	// hedge orders
+
+	if e.serverVersion >= minServerVerHedgeOrders {
+		message.addString("") // hedge type
+	}
+
+	if e.serverVersion >= minServerVerOptOutSmartRouting {
+		message.addBool(e.order.OptOutSmartRouting)
+	}
+
+	if e.serverVersion >= minServerVerPtaOrders {
+		message.addString(e.order.ClearingAccount)
+		message.addString(e.order.ClearingIntent)
+	}
+
+	if e.serverVersion >= minServerVerNotHeld {
+		message.addBool(e.order.NotHeld)
+	}
+
+	if e.serverVersion >= minServerVerDeltaNeutral {
+		if e.contract.DeltaNeutralContract.ContractId != "" {
+			message.addBool(true)
+			message.addString(e.contract.DeltaNeutralContract.ContractId)
+			message.addFloat64(e.contract.DeltaNeutralContract.Delta)
+			message.addFloat64(e.contract.DeltaNeutralContract.Price)
+		} else {
+			message.addBool(false)
+		}
+	}
+
+	if e.serverVersion >= minServerVerAlgoOrders {
+		strategy, params := e.order.AlgoStrategy, e.order.AlgoParams
+		if e.order.Algo != nil {
+			strategy, params = e.order.Algo.Strategy(), e.order.Algo.TagValues()
+		}
+
+		message.addString(strategy)
+		if strategy != "" {
+			message.addInt(len(params))
+			for _, param := range params {
+				message.addString(param.Tag)
+				message.addString(param.Value)
+			}
+		}
+	}
+
+	if e.serverVersion >= minServerVerAlgoId {
+		message.addString(e.order.AlgoId)
+	}
+
+	message.addBool(e.order.WhatIf)
+
+	if e.serverVersion >= minServerVersionLinking {
+		options := strings.Builder{}
+		for _, option := range e.order.OrderMiscOptions {
+			options.WriteString(option.Tag)
+			options.WriteString("=")
+			options.WriteString(option.Value)
+			options.WriteString(";")
+		}
+		message.addString(options.String())
+	}
+
+	if e.serverVersion >= minServerVerOrderSolicited {
+		message.addBool(e.order.Solicited)
+	}
+
+	if e.serverVersion >= minServerVerRandomizeSizeAndPrice {
+		message.addBool(e.order.RandomizeSize)
+		message.addBool(e.order.RandomizePrice)
+	}
+
+	if e.serverVersion >= minServerVerPeggedToBenchmark {
+		message.addInt(len(e.order.Conditions))
+		if len(e.order.Conditions) > 0 {
+			for _, condition := range e.order.Conditions {
+				encodeCondition(&message, condition)
+			}
+			message.addBool(e.order.ConditionsIgnoreRth)
+			message.addBool(e.order.ConditionsCancelOrder)
+		}
+
+		message.addString("") // adjusted order type
+		message.addString("") // trigger price
+		message.addString("") // limit price offset
+		message.addString("") // adjusted stop price
+		message.addString("") // adjusted stop limit price
+		message.addString("") // adjusted trailing amount
+		message.addInt(0)     // adjustable trailing unit
+	}
+
+	if e.serverVersion >= minServerVerExtOperator {
+		message.addString("") // ext operator
+	}
+
+	if e.serverVersion >= minServerVerSoftDollarTier {
+		message.addString("") // soft dollar tier name
+		message.addString("") // soft dollar tier value
+	}
+
+	if e.serverVersion >= minServerVerCashQty {
+		message.addOptionalFloat64(e.order.CashQuantity)
+	}
+
+	if e.serverVersion >= minServerVerDecisionMaker {
+		message.addString("") // mifid2 decision maker
+		message.addString("") // mifid2 decision algo
+	}
+
+	if e.serverVersion >= minServerVerMifidExecution {
+		message.addString("") // mifid2 execution trader
+		message.addString("") // mifid2 execution algo
+	}
+
+	if e.serverVersion >= minServerVerAutoPriceForHedge {
+		message.addBool(e.order.DontUseAutoPriceForHedge)
+	}
+
+	if e.serverVersion >= minServerVerOrderContainer {
+		message.addBool(e.order.IsOmsContainer)
+	}
+
+	if e.serverVersion >= minServerVerDPegOrders {
+		message.addBool(false) // discretionary up to limit price
+	}
+
+	if e.serverVersion >= minServerVerPriceMgmtAlgo {
+		if e.order.UsePriceMgmtAlgo {
+			message.addBool(true)
+		} else {
+			message.addString("")
+		}
+	}
+
+	if e.serverVersion >= minServerVerDuration {
+		message.addString("") // duration
+	}
+
+	if e.serverVersion >= minServerVerPostToAts {
+		message.addString("") // post to ATS
+	}
+
+	if e.serverVersion >= minServerVerAutoCancelParent {
+		message.addBool(false) // auto cancel parent
+	}
+
+	return message.Encode()
+}
+
+// encodeCondition writes the condition's type and conjunction, followed by its operator, value and contract fields.
+func encodeCondition(message *messageBuilder, condition Condition) {
+	message.addInt(int(condition.Type()))
+	if condition.conjunction() == ConjunctionOr {
+		message.addString("o")
+	} else {
+		message.addString("a")
+	}
+
+	switch c := condition.(type) {
+	case PriceCondition:
+		message.addBool(c.IsMore)
+		message.addDecimal(c.Price)
+		message.addInt(c.ContractId)
+		message.addString(c.Exchange)
+		message.addInt(c.TriggerMethod)
+	case TimeCondition:
+		message.addBool(c.IsMore)
+		message.addString(c.Time)
+	case MarginCondition:
+		message.addBool(c.IsMore)
+		message.addInt(c.Percent)
+	case ExecutionCondition:
+		message.addString(c.SecurityType)
+		message.addString(c.Exchange)
+		message.addString(c.Symbol)
+	case VolumeCondition:
+		message.addBool(c.IsMore)
+		message.addInt(c.Volume)
+		message.addInt(c.ContractId)
+		message.addString(c.Exchange)
+	case PercentChangeCondition:
+		message.addBool(c.IsMore)
+		message.addDecimal(c.ChangePercent)
+		message.addInt(c.ContractId)
+		message.addString(c.Exchange)
+	}
+}
+
+type cancelOrderEncoder struct {
+	serverVersion int
+	version       int
+	orderId       int
+}
+
+func (e *cancelOrderEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(cancelOrder)
+	message.addInt(e.version)
+	message.addInt(e.orderId)
+
+	return message.Encode()
+}
+
+type executionsEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	filter ExecutionFilter
+}
+
+func (e *executionsEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestExecutions)
+	message.addInt(e.version)
+
+	if e.serverVersion >= minServerVerExecutionDataChain {
+		message.addInt(e.requestId)
+	}
+
+	message.addInt(e.filter.ClientId)
+	message.addString(e.filter.Account)
+	message.addString(e.filter.Time)
+	message.addString(e.filter.Symbol)
+	message.addString(e.filter.SecurityType)
+	message.addString(e.filter.Exchange)
+	message.addString(e.filter.Side)
+
+	return message.Encode()
+}
+
+type accountSummaryEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	group string
+	tags  []AccountSummaryTag
+}
+
+func (e *accountSummaryEncoder) encode() string {
+	message := messageBuilder{}
+
+	tags := make([]string, len(e.tags))
+	for i, tag := range e.tags {
+		tags[i] = string(tag)
+	}
+
+	message.addInt(requestAccountSummary)
+	message.addInt(e.version)
+	message.addInt(e.requestId)
+	message.addString(e.group)
+	message.addString(strings.Join(tags, ","))
+
+	return message.Encode()
+}
+
+type positionsMultiEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	account   string
+	modelCode string
+}
+
+func (e *positionsMultiEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestPositionsMulti)
+	message.addInt(e.version)
+	message.addInt(e.requestId)
+	message.addString(e.account)
+	message.addString(e.modelCode)
+
+	return message.Encode()
+}
+
+type accountUpdatesMultiEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	account      string
+	modelCode    string
+	ledgerAndNLV bool
+}
+
+func (e *accountUpdatesMultiEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestAccountUpdatesMulti)
+	message.addInt(e.version)
+	message.addInt(e.requestId)
+	message.addString(e.account)
+	message.addString(e.modelCode)
+	message.addBool(e.ledgerAndNLV)
+
+	return message.Encode()
+}
+
+type pnlEncoder struct {
+	serverVersion int
+	requestId     int
+
+	account   string
+	modelCode string
+}
+
+func (e *pnlEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestPnl)
+	message.addInt(e.requestId)
+	message.addString(e.account)
+	message.addString(e.modelCode)
+
+	return message.Encode()
+}
+
+type pnlSingleEncoder struct {
+	serverVersion int
+	requestId     int
+
+	account    string
+	modelCode  string
+	contractId int
+}
+
+func (e *pnlSingleEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestPnlSingle)
+	message.addInt(e.requestId)
+	message.addString(e.account)
+	message.addString(e.modelCode)
+	message.addInt(e.contractId)
+
+	return message.Encode()
+}
+
+type replaceFaEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	faDataType int
+	data       string
+}
+
+func (e *replaceFaEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(replaceFa)
+	message.addInt(e.version)
+	message.addInt(e.faDataType)
+	message.addString(e.data)
+
+	if e.serverVersion >= minServerVerReplaceFaEnd {
+		message.addInt(e.requestId)
+	}
+
+	return message.Encode()
+}
+
+// encodeFaGroups converts financial advisor groups into their XML document
+func encodeFaGroups(groups []FaGroup) (string, error) {
+	document := faGroupsXml{}
+	for _, group := range groups {
+		document.Groups = append(document.Groups, faGroupXml{
+			Name:          group.Name,
+			Accounts:      faAccountsXml{VarName: "list", Accounts: group.Accounts},
+			DefaultMethod: group.DefaultMethod,
+		})
+	}
+
+	return encodeFaXml(document)
+}
+
+// encodeFaProfiles converts financial advisor profiles into their XML document
+func encodeFaProfiles(profiles []FaProfile) (string, error) {
+	document := faProfilesXml{}
+	for _, profile := range profiles {
+		document.Profiles = append(document.Profiles, faProfileXml{
+			Name:        profile.Name,
+			Type:        profile.Type,
+			Allocations: faAllocationsXml{VarName: "listOfAllocations", Allocations: profile.Allocations},
+		})
+	}
+
+	return encodeFaXml(document)
+}
+
+// encodeFaAliases converts account aliases into their XML document
+func encodeFaAliases(aliases []FaAlias) (string, error) {
+	return encodeFaXml(faAliasesXml{Aliases: aliases})
+}
+
+func encodeFaXml(document interface{}) (string, error) {
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(data), nil
+}
+
+type marketDataEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	contract           Contract
+	genericTicks       []int
+	snapshot           bool
+	regulatorySnapshot bool
+}
+
+func (e *marketDataEncoder) encode() string {
+	message := messageBuilder{}
+
+	genericTicks := make([]string, len(e.genericTicks))
+	for i, tick := range e.genericTicks {
+		genericTicks[i] = strconv.Itoa(tick)
+	}
+
+	message.addInt(requestMarketData)
+	message.addInt(e.version)
+	message.addInt(e.requestId)
+	message.addInt(e.contract.ContractId)
+	message.addString(e.contract.Symbol)
+	message.addString(e.contract.SecurityType)
+	message.addString(e.contract.LastTradeDateOrContractMonth)
+	message.addFloat64(e.contract.Strike)
+	message.addString(e.contract.Right)
+	message.addString(e.contract.Multiplier)
+	message.addString(e.contract.Exchange)
+	message.addString(e.contract.PrimaryExchange)
+	message.addString(e.contract.Currency)
+	message.addString(e.contract.LocalSymbol)
+	message.addString(e.contract.TradingClass)
+
+	// combo legs for BAG requests
+
+	if e.contract.SecurityType == "BAG" {
+		message.addInt(len(e.contract.ComboLegs))
+		for _, leg := range e.contract.ComboLegs {
+			message.addInt(leg.ContractId)
+			message.addInt(leg.Ratio)
+			message.addString(leg.Action)
+			message.addString(leg.Exchange)
+		}
+	}
+
+	if e.contract.DeltaNeutralContract.ContractId != "" {
+		message.addBool(true)
+		message.addString(e.contract.DeltaNeutralContract.ContractId)
+		message.addFloat64(e.contract.DeltaNeutralContract.Delta)
+		message.addFloat64(e.contract.DeltaNeutralContract.Price)
+	} else {
+		message.addBool(false)
+	}
+
+	message.addString(strings.Join(genericTicks, ","))
+	message.addBool(e.snapshot)
+
+	if e.serverVersion >= minServerVerReqSmartComponents {
+		message.addBool(e.regulatorySnapshot)
+	}
+
+	if e.serverVersion >= minServerVersionLinking {
+		// market data options
+		message.addString("")
+	}
+
+	return message.Encode()
+}
+
+type marketDepthEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	contract   Contract
+	rows       int
+	smartDepth bool
+}
+
+func (e *marketDepthEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestMarketDepth)
+	message.addInt(e.version)
+	message.addInt(e.requestId)
+	message.addInt(e.contract.ContractId)
+	message.addString(e.contract.Symbol)
+	message.addString(e.contract.SecurityType)
+	message.addString(e.contract.LastTradeDateOrContractMonth)
+	message.addFloat64(e.contract.Strike)
+	message.addString(e.contract.Right)
+	message.addString(e.contract.Multiplier)
+	message.addString(e.contract.Exchange)
+
+	if e.serverVersion >= minServerVerMktDepthPrimExchange {
+		message.addString(e.contract.PrimaryExchange)
+	}
+
+	message.addString(e.contract.Currency)
+	message.addString(e.contract.LocalSymbol)
+	message.addString(e.contract.TradingClass)
+	message.addInt(e.rows)
+
+	if e.serverVersion >= minServerVerSmartDepth {
+		message.addBool(e.smartDepth)
+	}
+
+	if e.serverVersion >= minServerVersionLinking {
+		// market depth options
+		message.addString("")
+	}
+
+	return message.Encode()
+}
+
+type historicalDataEncoder struct {
+	serverVersion int
+	version       int
+	requestId     int
+
+	contract     Contract
+	endTime      string
+	duration     Duration
+	barSize      BarSize
+	whatToShow   WhatToShow
+	useRth       bool
+	formatDate   int
+	keepUpToDate bool
+}
+
+func (e *historicalDataEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestHistoricalData)
+	if e.serverVersion < minServerVerSyntRealtimeBars {
+		message.addInt(e.version)
+	}
+	message.addInt(e.requestId)
+	message.addInt(e.contract.ContractId)
+	message.addString(e.contract.Symbol)
+	message.addString(e.contract.SecurityType)
+	message.addString(e.contract.LastTradeDateOrContractMonth)
+	message.addFloat64(e.contract.Strike)
+	message.addString(e.contract.Right)
+	message.addString(e.contract.Multiplier)
+	message.addString(e.contract.Exchange)
+	message.addString(e.contract.PrimaryExchange)
+	message.addString(e.contract.Currency)
+	message.addString(e.contract.LocalSymbol)
+	message.addString(e.contract.TradingClass)
+	message.addBool(e.contract.IncludeExpired)
+	message.addString(e.endTime)
+	message.addString(string(e.barSize))
+	message.addString(e.duration.String())
+	message.addBool(e.useRth)
+	message.addString(string(e.whatToShow))
+	message.addInt(e.formatDate)
+
+	// combo legs for BAG requests
+
+	if e.contract.SecurityType == "BAG" {
+		message.addInt(len(e.contract.ComboLegs))
+		for _, leg := range e.contract.ComboLegs {
+			message.addInt(leg.ContractId)
+			message.addInt(leg.Ratio)
+			message.addString(leg.Action)
+			message.addString(leg.Exchange)
+		}
+	}
+
+	if e.serverVersion >= minServerVerSyntRealtimeBars {
+		message.addBool(e.keepUpToDate)
+	}
+
+	if e.serverVersion >= minServerVersionLinking {
+		// chart options
+		message.addString("")
+	}
+
+	return message.Encode()
+}
+
+type historicalTicksEncoder struct {
+	serverVersion int
+	requestId     int
+
+	contract      Contract
+	startTime     string
+	endTime       string
+	numberOfTicks int
+	whatToShow    WhatToShow
+	useRth        bool
+	ignoreSize    bool
+}
+
+func (e *historicalTicksEncoder) encode() string {
+	message := messageBuilder{}
+
+	message.addInt(requestHistoricalTicks)
+	message.addInt(e.requestId)
+	message.addInt(e.contract.ContractId)
+	message.addString(e.contract.Symbol)
+	message.addString(e.contract.SecurityType)
+	message.addString(e.contract.LastTradeDateOrContractMonth)
+	message.addFloat64(e.contract.Strike)
+	message.addString(e.contract.Right)
+	message.addString(e.contract.Multiplier)
+	message.addString(e.contract.Exchange)
+	message.addString(e.contract.PrimaryExchange)
+	message.addString(e.contract.Currency)
+	message.addString(e.contract.LocalSymbol)
+	message.addString(e.contract.TradingClass)
+	message.addBool(e.contract.IncludeExpired)
+	message.addString(e.startTime)
+	message.addString(e.endTime)
+	message.addInt(e.numberOfTicks)
+	message.addString(string(e.whatToShow))
+	message.addBool(e.useRth)
+	message.addBool(e.ignoreSize)
+	message.addString("") // misc options
+
+	return message.Encode()
+}
@@ -1,7 +1,9 @@
 package ibapi
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -98,3 +100,302 @@ func TestContractDetailsEncoder(t *testing.T) {
 
 	assert.Equal(t, "9\x000\x002\x000\x00ES\x00FUT\x002021\x000.000000\x00\x00\x00\x00\x00USD\x00\x00\x000\x00\x00\x00", request.encode())
 }
+
+func TestPlaceOrderEncoder(t *testing.T) {
+	contract := Contract{
+		Symbol:       "ES",
+		LocalSymbol:  "ESU2",
+		SecurityType: "FUT",
+		Currency:     "USD",
+		Exchange:     "GLOBEX",
+	}
+
+	order := NewOrder()
+	order.Action = "BUY"
+	order.TotalQuantity = 2
+	order.OrderType = "LMT"
+	order.LimitPrice = 4100.25
+	order.TimeInForce = "DAY"
+	order.Account = "DU1234"
+	order.Transmit = true
+	order.OutsideRth = true
+
+	request := placeOrderEncoder{
+		serverVersion: minServerVerAutoCancelParent,
+		version:       45,
+		orderId:       13,
+		contract:      contract,
+		order:         order,
+	}
+
+	expected := []string{
+		"3", "13",
+		// contract
+		"0", "ES", "FUT", "", "0.000000", "", "", "GLOBEX", "", "USD", "ESU2", "", "", "",
+		// main order fields
+		"BUY", "2", "LMT", "4100.250000", "",
+		// extended order fields
+		"DAY", "", "DU1234", "", "0", "", "1", "0", "0", "0", "0", "0", "1", "0",
+		// shares allocation, discretionary amount, good after/till
+		"", "0.000000", "", "",
+		// financial advisor, model code
+		"", "", "", "", "",
+		// short sale slot
+		"0", "", "0",
+		// oca type, rule 80A, settling firm, all or none, min quantity, percent offset
+		"0", "", "", "0", "", "",
+		// deprecated fields, auction strategy, box orders, peg to stock
+		"0", "0", "", "0", "", "", "", "", "",
+		// override percentage constraints
+		"0",
+		// volatility orders
+		"", "", "", "",
+		// continuous update, reference price type, trail stop price, trailing percent
+		"0", "", "", "",
+		// scale orders
+		"", "", "", "", "", "",
+		// hedge type, opt out smart routing, clearing account, clearing intent, not held
+		"", "0", "", "", "0",
+		// delta neutral contract
+		"0",
+		// algo strategy, algo id
+		"", "",
+		// what if, misc options, solicited, randomize size/price
+		"0", "", "0", "0", "0",
+		// conditions, adjusted order fields
+		"0", "", "", "", "", "", "", "0",
+		// ext operator, soft dollar tier, cash quantity
+		"", "", "", "",
+		// mifid2
+		"", "", "", "",
+		// auto price for hedge, oms container, discretionary up to limit, price management algo
+		"0", "0", "0", "",
+		// duration, post to ats, auto cancel parent
+		"", "", "0",
+	}
+
+	assert.Equal(t, strings.Join(expected, "\x00")+"\x00", request.encode())
+
+	t.Run("with a zero limit price", func(t *testing.T) {
+		zero := request
+		zero.order.LimitPrice = 0
+
+		assert.Contains(t, zero.encode(), "\x00BUY\x002\x00LMT\x000.000000\x00\x00DAY\x00")
+	})
+
+	t.Run("with algo parameters", func(t *testing.T) {
+		request.order.Algo = AdaptiveParams{Priority: "Patient"}
+
+		assert.Contains(t, request.encode(), "\x000\x00Adaptive\x001\x00adaptivePriority\x00Patient\x00\x000\x00")
+	})
+
+	t.Run("with conditions", func(t *testing.T) {
+		request.order.Conditions = []Condition{
+			PriceCondition{IsMore: true, Price: 4100.25, ContractId: 495512551, Exchange: "GLOBEX", TriggerMethod: 2},
+			TimeCondition{Conjunction: ConjunctionOr, Time: "20220610 16:00:00"},
+		}
+		request.order.ConditionsCancelOrder = true
+
+		assert.Contains(t, request.encode(), "\x002\x001\x00a\x001\x004100.25\x00495512551\x00GLOBEX\x002\x003\x00o\x000\x0020220610 16:00:00\x000\x001\x00")
+	})
+}
+
+func TestCancelOrderEncoder(t *testing.T) {
+	request := cancelOrderEncoder{
+		serverVersion: minServerVerHistoricalSchedule,
+		version:       1,
+		orderId:       13,
+	}
+
+	assert.Equal(t, "4\x001\x0013\x00", request.encode())
+}
+
+func TestExecutionsEncoder(t *testing.T) {
+	request := executionsEncoder{
+		serverVersion: minServerVerLastLiquidity,
+		version:       3,
+		requestId:     9001,
+		filter: ExecutionFilter{
+			Account: "DU1234",
+			Symbol:  "ES",
+			Side:    "BUY",
+		},
+	}
+
+	assert.Equal(t, "7\x003\x009001\x000\x00DU1234\x00\x00ES\x00\x00\x00BUY\x00", request.encode())
+}
+
+func TestAccountSummaryEncoder(t *testing.T) {
+	request := accountSummaryEncoder{
+		serverVersion: minServerVerAccountSummary,
+		version:       1,
+		requestId:     9001,
+		group:         "All",
+		tags:          []AccountSummaryTag{AccountSummaryNetLiquidation, AccountSummaryBuyingPower, AccountSummaryLedgerCurrency("USD")},
+	}
+
+	assert.Equal(t, "62\x001\x009001\x00All\x00NetLiquidation,BuyingPower,$LEDGER:USD\x00", request.encode())
+}
+
+func TestPositionsMultiEncoder(t *testing.T) {
+	request := positionsMultiEncoder{
+		serverVersion: minServerVerModelsSupport,
+		version:       1,
+		requestId:     9001,
+		account:       "DU1234",
+		modelCode:     "Growth",
+	}
+
+	assert.Equal(t, "74\x001\x009001\x00DU1234\x00Growth\x00", request.encode())
+}
+
+func TestAccountUpdatesMultiEncoder(t *testing.T) {
+	request := accountUpdatesMultiEncoder{
+		serverVersion: minServerVerModelsSupport,
+		version:       1,
+		requestId:     9001,
+		account:       "DU1234",
+		modelCode:     "",
+		ledgerAndNLV:  true,
+	}
+
+	assert.Equal(t, "76\x001\x009001\x00DU1234\x00\x001\x00", request.encode())
+}
+
+func TestPnLEncoders(t *testing.T) {
+	request := pnlEncoder{
+		serverVersion: minServerVerRealizedPnl,
+		requestId:     9001,
+		account:       "DU1234",
+	}
+
+	assert.Equal(t, "92\x009001\x00DU1234\x00\x00", request.encode())
+
+	single := pnlSingleEncoder{
+		serverVersion: minServerVerRealizedPnl,
+		requestId:     9002,
+		account:       "DU1234",
+		contractId:    265598,
+	}
+
+	assert.Equal(t, "94\x009002\x00DU1234\x00\x00265598\x00", single.encode())
+}
+
+func TestReplaceFaEncoder(t *testing.T) {
+	request := replaceFaEncoder{
+		serverVersion: minServerVerReplaceFaEnd,
+		version:       1,
+		requestId:     9001,
+		faDataType:    faAliases,
+		data:          "<ListOfAccountAliases/>",
+	}
+
+	assert.Equal(t, "19\x001\x003\x00<ListOfAccountAliases/>\x009001\x00", request.encode())
+
+	request.serverVersion = minServerVerReplaceFaEnd - 1
+
+	assert.Equal(t, "19\x001\x003\x00<ListOfAccountAliases/>\x00", request.encode())
+}
+
+func TestEncodeFaGroups(t *testing.T) {
+	groups := []FaGroup{
+		{Name: "Equal_Quantity", Accounts: []string{"DU119915", "DU119916"}, DefaultMethod: "EqualQuantity"},
+	}
+
+	data, err := encodeFaGroups(groups)
+
+	assert.Nil(t, err)
+	assert.Contains(t, data, `<ListOfAccts varName="list">`)
+	assert.Contains(t, data, "<String>DU119916</String>")
+
+	decoded, err := decodeFaGroups(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, groups, decoded)
+}
+
+func TestMarketDataEncoder(t *testing.T) {
+	request := marketDataEncoder{
+		serverVersion: maxClientVer,
+		version:       11,
+		requestId:     9001,
+		contract: Contract{
+			Symbol:       "AAPL",
+			SecurityType: "STK",
+			Exchange:     "SMART",
+			Currency:     "USD",
+		},
+		genericTicks: []int{233, 236},
+		snapshot:     false,
+	}
+
+	assert.Equal(t, "1\x0011\x009001\x000\x00AAPL\x00STK\x00\x000.000000\x00\x00\x00SMART\x00\x00USD\x00\x00\x000\x00233,236\x000\x000\x00\x00", request.encode())
+}
+
+func TestMarketDepthEncoder(t *testing.T) {
+	request := marketDepthEncoder{
+		serverVersion: maxClientVer,
+		version:       5,
+		requestId:     9001,
+		contract: Contract{
+			ContractId:   495512551,
+			Symbol:       "ES",
+			SecurityType: "FUT",
+			Exchange:     "GLOBEX",
+			Currency:     "USD",
+		},
+		rows:       10,
+		smartDepth: false,
+	}
+
+	assert.Equal(t, "10\x005\x009001\x00495512551\x00ES\x00FUT\x00\x000.000000\x00\x00\x00GLOBEX\x00\x00USD\x00\x00\x0010\x000\x00\x00", request.encode())
+}
+
+func TestHistoricalDataEncoder(t *testing.T) {
+	request := historicalDataEncoder{
+		serverVersion: maxClientVer,
+		version:       6,
+		requestId:     9001,
+		contract: Contract{
+			Symbol:       "AAPL",
+			SecurityType: "STK",
+			Exchange:     "SMART",
+			Currency:     "USD",
+		},
+		endTime:    formatHistoricalTime(time.Date(2022, 6, 10, 20, 0, 0, 0, time.UTC)),
+		duration:   Days(5),
+		barSize:    BarSize1Hour,
+		whatToShow: WhatToShowTrades,
+		useRth:     true,
+		formatDate: 2,
+	}
+
+	assert.Equal(t, "20\x009001\x000\x00AAPL\x00STK\x00\x000.000000\x00\x00\x00SMART\x00\x00USD\x00\x00\x000\x0020220610 20:00:00 GMT\x001 hour\x005 D\x001\x00TRADES\x002\x000\x00\x00", request.encode())
+
+	t.Run("keep up to date", func(t *testing.T) {
+		request.endTime = ""
+		request.keepUpToDate = true
+
+		assert.Equal(t, "20\x009001\x000\x00AAPL\x00STK\x00\x000.000000\x00\x00\x00SMART\x00\x00USD\x00\x00\x000\x00\x001 hour\x005 D\x001\x00TRADES\x002\x001\x00\x00", request.encode())
+	})
+}
+
+func TestHistoricalTicksEncoder(t *testing.T) {
+	request := historicalTicksEncoder{
+		serverVersion: maxClientVer,
+		requestId:     9001,
+		contract: Contract{
+			Symbol:       "AAPL",
+			SecurityType: "STK",
+			Exchange:     "SMART",
+			Currency:     "USD",
+		},
+		endTime:       formatHistoricalTime(time.Date(2022, 6, 10, 20, 0, 0, 0, time.UTC)),
+		numberOfTicks: 1000,
+		whatToShow:    WhatToShowBidAsk,
+		useRth:        true,
+		ignoreSize:    true,
+	}
+
+	assert.Equal(t, "96\x009001\x000\x00AAPL\x00STK\x00\x000.000000\x00\x00\x00SMART\x00\x00USD\x00\x00\x000\x00\x0020220610 20:00:00 GMT\x001000\x00BID_ASK\x001\x001\x00\x00", request.encode())
+}
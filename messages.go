@@ -2,6 +2,7 @@ package ibapi
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -195,6 +196,29 @@ func (b *messageBuilder) addFloat64(num float64) {
 	fmt.Fprintf(&b.builder, "%f\x00", num)
 }
 
+// addOptionalInt writes an empty field for zero or unset values.
+func (b *messageBuilder) addOptionalInt(i int) {
+	if i == 0 || i == math.MaxInt32 {
+		b.addString("")
+	} else {
+		b.addInt(i)
+	}
+}
+
+// addOptionalFloat64 writes an empty field for unset values, math.MaxFloat64. Zero is a value.
+func (b *messageBuilder) addOptionalFloat64(num float64) {
+	if num == math.MaxFloat64 {
+		b.addString("")
+	} else {
+		b.addFloat64(num)
+	}
+}
+
+// addDecimal writes a quantity using the shortest representation, e.g. 100 or 0.5.
+func (b *messageBuilder) addDecimal(num float64) {
+	fmt.Fprintf(&b.builder, "%s\x00", strconv.FormatFloat(num, 'f', -1, 64))
+}
+
 func (b *messageBuilder) addBool(flag bool) {
 	if flag {
 		fmt.Fprintf(&b.builder, "1\x00")
@@ -250,3 +274,20 @@ func (s *parser) readString() string {
 	s.fields = s.fields[1:]
 	return result
 }
+
+func (s *parser) readBool() bool {
+	result := s.readString()
+
+	switch result {
+	case "", "0", "false":
+		return false
+	case "1", "true":
+		return true
+	}
+
+	num, err := strconv.Atoi(result)
+	if err != nil {
+		panic(err)
+	}
+	return num != 0
+}
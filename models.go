@@ -1,6 +1,12 @@
 package ibapi
 
-import "time"
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
 
 type (
 	// Describes an instrument's definition
@@ -136,6 +142,13 @@ type (
 		Count  int       // The number of trades during the bar's timespan (only available for TRADES)
 	}
 
+	// BarUpdate is a change of the last bar of HistoricalDataUpdates.
+	BarUpdate struct {
+		Bar    Bar           // The bar.
+		NewBar bool          // The bar starts after the previous one, rather than revising it.
+		Error  *RequestError // The error ending the stream, set on its last update instead of a bar.
+	}
+
 	Trade struct {
 		TickType          string         // tick type: "Last" or "AllLast"
 		Time              time.Time      // The trade's date and time (either as a yyyymmss hh:mm:ss formatted string or as system time according to the request). Time zone is the TWS time zone chosen on login.
@@ -164,4 +177,1442 @@ type (
 		BidPastLow  bool
 		AskPastHigh bool
 	}
+
+	// Midpoint is a historical midpoint tick.
+	Midpoint struct {
+		Time  time.Time // The time of the tick.
+		Price float64   // The midpoint price.
+	}
+
+	// HistoricalTickData holds the result of HistoricalTicks, only the ticks of the requested kind are set.
+	HistoricalTickData struct {
+		Trades    []Trade
+		BidAsks   []BidAsk
+		Midpoints []Midpoint
+	}
+
+	// Order describes an order to be sent to TWS. Optional prices are unset at math.MaxFloat64, see NewOrder.
+	// Other numeric fields left at zero are sent as unset.
+	Order struct {
+		OrderId  int // The API client's order id, set on open orders. The id is passed to PlaceOrder, or returned in the OrderGroup of PlaceOrderGroup, and the struct is left unchanged.
+		ClientId int // The API client id which placed the order.
+		PermId   int // The Host order identifier.
+
+		Action        string  // Identifies the side. BUY, SELL or SSHORT.
+		TotalQuantity float64 // The number of positions being bought/sold.
+
+		// The order's type. Common values are:
+		// MKT - market
+		// LMT - limit
+		// STP - stop
+		// STP LMT - stop limit
+		// TRAIL - trailing stop
+		// MOC - market on close
+		// LOC - limit on close
+		OrderType string
+
+		LimitPrice float64 // The LIMIT price. Used for limit, stop-limit and relative orders. Zero is a valid price, e.g. for spreads.
+		AuxPrice   float64 // Generic field to contain the stop price for STP LMT orders, trailing amount, etc.
+
+		// The time in force. Valid values are:
+		// DAY - Valid for the day only.
+		// GTC - Good until canceled. The order will continue to work within the system and in the marketplace until it executes or is canceled.
+		// IOC - Immediate or Cancel. Any portion that is not filled as soon as it becomes available in the market is canceled.
+		// GTD - Good until Date. It will remain working within the system and in the marketplace until it executes or until the close of the market on the date specified.
+		// OPG - Use OPG to send a market-on-open (MOO) or limit-on-open (LOO) order.
+		// FOK - If the entire Fill-or-Kill order does not execute as soon as it becomes available, the entire order is canceled.
+		// DTC - Day until Canceled.
+		TimeInForce string
+
+		OcaGroup string // One-Cancels-All group identifier.
+
+		// Tells how to handle remaining orders in an OCA group when one order or part of an order executes. Valid values are:
+		// 1 - Cancel all remaining orders with block.
+		// 2 - Remaining orders are proportionately reduced in size with block.
+		// 3 - Remaining orders are proportionately reduced in size with no block.
+		OcaType int
+
+		OrderRef string // A reference assigned to the order, visible in TWS.
+
+		// Specifies whether the order will be transmitted by TWS. If set to false, the order will be created at TWS but will not be sent.
+		// Orders are only executed when Transmit is true.
+		Transmit bool
+
+		ParentId    int  // The order ID of the parent order, used for bracket and auto trailing stop orders.
+		BlockOrder  bool // If set to true, specifies that the order is an ISE Block order.
+		SweepToFill bool // If set to true, specifies that the order is a Sweep-to-Fill order.
+		DisplaySize int  // The publicly disclosed order size, used when placing Iceberg orders.
+
+		// Specifies how Simulated Stop, Stop-Limit and Trailing Stop orders are triggered. Valid values are:
+		// 0 - The default value. The "double bid/ask" function will be used for orders for OTC stocks and US options. All other orders will used the "last" function.
+		// 1 - use "double bid/ask" function, where stop orders are triggered based on two consecutive bid or ask prices.
+		// 2 - "last" function, where stop orders are triggered based on the last price.
+		// 3 - double last function.
+		// 4 - bid/ask function.
+		// 7 - last or bid/ask function.
+		// 8 - mid-point function.
+		TriggerMethod int
+
+		OutsideRth bool // If set to true, allows orders to also trigger or fill outside of regular trading hours.
+		Hidden     bool // If set to true, the order will not be visible when viewing the market depth. This option only applies to orders routed to the NASDAQ exchange.
+
+		GoodAfterTime string // Specifies the date and time after which the order will be active. Format: yyyymmdd hh:mm:ss {optional Timezone}.
+		GoodTillDate  string // The date and time until the order will be active. You must enter GTD as the time in force to use this string. The trade's "Good Till Date," format "yyyyMMdd HH:mm:ss (optional time zone)".
+
+		Account string // The account the trade will be allocated to.
+
+		// For institutional customers only. Valid values are O (open) and C (close).
+		// Available for institutional clients to determine if this order is to open or close a position.
+		OpenClose string
+
+		Origin int // The order's origin. 0 - customer, 1 - firm. Same as TWS "Origin" field.
+
+		ShortSaleSlot      int    // For institutions only. 1 - clearing broker, 2 - third party.
+		DesignatedLocation string // Used only when ShortSaleSlot is 2.
+		ExemptCode         int    // Only available with IB Execution-Only accounts with applicable securities. Mark order as exempt from short sale uptick rule.
+
+		DiscretionaryAmount float64 // The amount off the limit price allowed for discretionary orders.
+
+		FaGroup      string // The Financial Advisor group the trade will be allocated to. Use an empty string if not applicable.
+		FaMethod     string // The Financial Advisor allocation method: PctChange, AvailableEquity, NetLiq or EqualQuantity.
+		FaPercentage string // The Financial Advisor percentage concerning the trade's allocation.
+		FaProfile    string // The Financial Advisor allocation profile the trade will be allocated to.
+		ModelCode    string // Model code.
+
+		Rule80A       string  // Individual = 'I', Agency = 'A', AgentOtherMember = 'W', IndividualPTIA = 'J', AgencyPTIA = 'U', AgentOtherMemberPTIA = 'M', IndividualPT = 'K', AgencyPT = 'Y', AgentOtherMemberPT = 'N'.
+		SettlingFirm  string  // Institutional only.
+		AllOrNone     bool    // Indicates whether or not all the order has to be filled on a single execution.
+		MinQuantity   int     // Identifies a minimum quantity order type.
+		PercentOffset float64 // The percent offset amount for relative orders.
+
+		TrailStopPrice  float64 // Trail stop price for TRAIL LIMIT orders.
+		TrailingPercent float64 // Specifies the trailing amount of a trailing stop order as a percentage.
+
+		OverridePercentageConstraints bool // Overrides TWS constraints. Precautionary constraints are defined on the TWS Presets page, and help ensure that your price and size order values are reasonable.
+
+		Volatility     float64 // The option price in volatility, as calculated by TWS' Option Analytics.
+		VolatilityType int     // The type of volatility. 1 - daily, 2 - annual.
+
+		OptOutSmartRouting bool   // Use to opt out of default SmartRouting for orders routed directly to ASX.
+		ClearingAccount    string // For execution-only clients to know where do they want the shares to be cleared at.
+		ClearingIntent     string // For execution-only clients to know where do they want the shares to be cleared at. IB, Away or PTA.
+		NotHeld            bool   // Used for brokers that need to know when an order is not held.
+
+		Algo         Algo       // Typed parameters of an IB algorithm, e.g. AdaptiveParams. When set, takes precedence over AlgoStrategy and AlgoParams.
+		AlgoStrategy string     // The algorithm strategy.
+		AlgoParams   []TagValue // The list of parameters for the IB algorithm.
+		AlgoId       string     // Identifies orders generated by algorithmic trading.
+
+		Conditions            []Condition // Conditions that activate or cancel the order, e.g. PriceCondition.
+		ConditionsIgnoreRth   bool        // Allows conditions to be satisfied outside regular trading hours.
+		ConditionsCancelOrder bool        // Cancels the order, rather than activating it, when the conditions are met.
+
+		Solicited bool // Whether the order was solicited.
+
+		WhatIf bool // Requests the margin and commission impact of the order without placing it. See WhatIfOrder.
+
+		RandomizeSize  bool // Randomizes the order's size. Only for Volatility and Pegged to Volatility orders.
+		RandomizePrice bool // Randomizes the order's price. Only for Volatility and Pegged to Volatility orders.
+
+		CashQuantity float64 // The cash quantity of the order. Only available for forex and crypto currency orders.
+
+		OrderComboLegs          []OrderComboLeg // List of per leg prices for BAG orders.
+		SmartComboRoutingParams []TagValue      // Advanced parameters for Smart combo routing.
+		OrderMiscOptions        []TagValue      // Reserved for internal use.
+
+		DontUseAutoPriceForHedge bool // Don't use auto price for hedge.
+		IsOmsContainer           bool // Set to true to create tickets from API orders when TWS is used as an OMS.
+		UsePriceMgmtAlgo         bool // Use the price management algo.
+
+		FilledQuantity float64 // The quantity filled, reported for completed orders.
+		ParentPermId   int     // The perm id of the parent order, reported for completed orders.
+	}
+
+	// OrderComboLeg describes the price of a combo order leg.
+	OrderComboLeg struct {
+		Price float64 // The price of the leg, math.MaxFloat64 when unset.
+	}
+
+	// OrderStatus describes the current status of an order.
+	OrderStatus struct {
+		OrderId int // The order's client id.
+
+		// The current status of the order. Possible values:
+		// PendingSubmit - indicates that you have transmitted the order, but have not yet received confirmation that it has been accepted by the order destination.
+		// PendingCancel - indicates that you have sent a request to cancel the order but have not yet received cancel confirmation from the order destination.
+		// PreSubmitted - indicates that a simulated order type has been accepted by the IB system and that this order has yet to be elected.
+		// Submitted - indicates that your order has been accepted by the system.
+		// ApiCancelled - after an order has been submitted and before it has been acknowledged, an API client client can request its cancelation, producing this state.
+		// Cancelled - indicates that the balance of your order has been confirmed canceled by the IB system.
+		// Filled - indicates that the order has been completely filled.
+		// Inactive - indicates that the order was received by the system but is no longer active because it was rejected or canceled.
+		Status string
+
+		Filled           float64 // Number of filled positions.
+		Remaining        float64 // The remnant positions.
+		AverageFillPrice float64 // Average filling price.
+		PermId           int     // The order's permId used by the TWS to identify orders.
+		ParentId         int     // Parent's id. Used for bracket and auto trailing stop orders.
+		LastFillPrice    float64 // Price at which the last positions were filled.
+		ClientId         int     // API client which submitted the order.
+		WhyHeld          string  // This field is used to identify an order held when TWS is trying to locate shares for a short sell. The value used to indicate this is 'locate'.
+		MarketCapPrice   float64 // If an order has been capped, this indicates the current capped price.
+	}
+
+	// OrderState describes the margin and commission impact of an order.
+	OrderState struct {
+		Status string // The order's current status.
+
+		InitMarginBefore     float64 // The account's current initial margin.
+		MaintMarginBefore    float64 // The account's current maintenance margin.
+		EquityWithLoanBefore float64 // The account's current equity with loan.
+		InitMarginChange     float64 // The change of the account's initial margin.
+		MaintMarginChange    float64 // The change of the account's maintenance margin.
+		EquityWithLoanChange float64 // The change of the account's equity with loan.
+		InitMarginAfter      float64 // The order's impact on the account's initial margin.
+		MaintMarginAfter     float64 // The order's impact on the account's maintenance margin.
+		EquityWithLoanAfter  float64 // Shows the impact the order would have on the account's equity with loan.
+
+		Commission         float64 // The order's generated commission.
+		MinCommission      float64 // The execution's minimum commission.
+		MaxCommission      float64 // The execution's maximum commission.
+		CommissionCurrency string  // The generated commission currency.
+		WarningText        string  // If the order is warranted, a descriptive message will be provided.
+
+		CompletedTime   string // The time the order was completed, reported for completed orders.
+		CompletedStatus string // The reason the order was completed, e.g. Filled or Cancelled by Trader.
+	}
+
+	// OpenOrder describes an order as reported by TWS.
+	OpenOrder struct {
+		Contract   Contract   // The order's contract.
+		Order      Order      // The currently active order.
+		OrderState OrderState // The order's margin and commission impact.
+	}
+
+	// CompletedOrder describes an order that was filled or cancelled.
+	CompletedOrder struct {
+		Contract   Contract   // The order's contract.
+		Order      Order      // The completed order.
+		OrderState OrderState // The order's final state.
+	}
+
+	// OrderError is an error reported by TWS for an order, e.g. a rejection.
+	OrderError struct {
+		OrderId int    // The id of the order the error refers to.
+		Code    int    // The TWS error code.
+		Message string // The error description.
+	}
+
+	// RequestError is an error reported by TWS for a request, e.g. a rejected subscription.
+	RequestError struct {
+		RequestId int    // The id of the request the error refers to.
+		Code      int    // The TWS error code.
+		Message   string // The error description.
+	}
+
+	// OrderEvent is a notification about an order. Exactly one of Status, OpenOrder or Error is set.
+	OrderEvent struct {
+		OrderId   int          // The order's client id.
+		Status    *OrderStatus // Set for order status changes.
+		OpenOrder *OpenOrder   // Set for open order descriptions.
+		Error     *OrderError  // Set for errors reported for the order.
+	}
+
+	// ExecutionFilter selects the executions returned by an executions request. Empty fields match all executions.
+	ExecutionFilter struct {
+		ClientId     int    // The API client which placed the order.
+		Account      string // The account to which the order was allocated to.
+		Time         string // Time from which the executions will be returned yyyymmdd hh:mm:ss. Only those executions reported after the specified time will be returned.
+		Symbol       string // The instrument's symbol.
+		SecurityType string // The Contract's security's type (i.e. STK, OPT...).
+		Exchange     string // The exchange at which the execution was produced.
+		Side         string // The Contract's side (BUY or SELL).
+	}
+
+	// Execution describes an order's execution.
+	Execution struct {
+		Contract Contract // The executed contract.
+
+		OrderId  int     // The API client's order Id. May not be unique to an account.
+		ExecId   string  // The execution's identifier. Each partial fill has a separate ExecId. A correction is indicated by an ExecId which differs from a previous ExecId in only the digits after the final period, e.g. an ExecId ending in ".02" would be a correction of a previous execution with an ExecId ending in ".01".
+		Time     string  // The execution's server time.
+		Account  string  // The account to which the order was allocated.
+		Exchange string  // The exchange where the execution took place.
+		Side     string  // Specifies if the transaction was buy or sale BOT for bought, SLD for sold.
+		Shares   float64 // The number of shares filled.
+		Price    float64 // The order's execution price excluding commissions.
+		PermId   int     // The TWS order identifier. The PermId can be 0 for trades originating outside IB.
+		ClientId int     // The API client from which the order was submitted.
+
+		Liquidation        int     // Identifies whether an execution occurred because of an IB-initiated liquidation.
+		CumulativeQuantity float64 // Cumulative quantity. Used in regular trades, combo trades and legs of the combo.
+		AveragePrice       float64 // Average price. Used in regular trades, combo trades and legs of the combo. Does not include commissions.
+		OrderRef           string  // The OrderRef is a user-customizable string that can be set from the API or TWS and will be associated with an order for its lifetime.
+		EvRule             string  // The Economic Value Rule name and the respective optional argument. The two values should be separated by a colon.
+		EvMultiplier       float64 // Tells you approximately how much the market value of a contract would change if the price were to change by 1.
+		ModelCode          string  // Model code.
+		LastLiquidity      int     // The liquidity type of the execution. 1 - added liquidity, 2 - removed liquidity, 3 - liquidity routed out.
+
+		CommissionReport CommissionReport // The commission charged for the execution, when it has been reported.
+	}
+
+	// CommissionReport describes the commissions charged for an execution.
+	CommissionReport struct {
+		ExecId              string  // The execution's id this commission belongs to.
+		Commission          float64 // The commissions cost.
+		Currency            string  // The reporting currency.
+		RealizedPnL         float64 // The realized profit and loss.
+		Yield               float64 // The income return.
+		YieldRedemptionDate int     // Date expressed in yyyymmdd format.
+	}
+
+	// AdaptiveParams are the parameters of the Adaptive algo, which combines IB's Smartrouting capabilities with user-defined priority settings.
+	AdaptiveParams struct {
+		Priority string // Urgent, Normal or Patient.
+	}
+
+	// TwapParams are the parameters of the TWAP algo, which aims to achieve the time-weighted average price calculated from the time you submit the order to the time it completes.
+	TwapParams struct {
+		StrategyType     string // Marketable, Matching Midpoint, Matching Same Side or Matching Last.
+		StartTime        string // Algorithm starting time, e.g. 09:00:00 US/Eastern.
+		EndTime          string // Algorithm ending time, e.g. 16:00:00 US/Eastern.
+		AllowPastEndTime bool   // Allow trading past end time.
+	}
+
+	// VwapParams are the parameters of the VWAP algo, which seeks to achieve the Volume-Weighted Average price calculated from the time you submit the order to the close of the market.
+	VwapParams struct {
+		MaxPctVol        float64 // Maximum percentage of average daily volume, between 0.01 and 0.5.
+		StartTime        string  // Algorithm starting time, e.g. 09:00:00 US/Eastern.
+		EndTime          string  // Algorithm ending time, e.g. 16:00:00 US/Eastern.
+		AllowPastEndTime bool    // Allow trading past end time.
+		NoTakeLiq        bool    // Attempt to never take liquidity.
+	}
+
+	// ArrivalPriceParams are the parameters of the Arrival Price algo, which attempts to achieve, over the course of the order, the bid/ask midpoint price at the time the order is submitted.
+	ArrivalPriceParams struct {
+		MaxPctVol        float64 // Maximum percentage of average daily volume, between 0.1 and 0.5.
+		RiskAversion     string  // Get Done, Aggressive, Neutral or Passive.
+		StartTime        string  // Algorithm starting time, e.g. 09:00:00 US/Eastern.
+		EndTime          string  // Algorithm ending time, e.g. 16:00:00 US/Eastern.
+		ForceCompletion  bool    // Attempt completion by the end of the day.
+		AllowPastEndTime bool    // Allow trading past end time.
+	}
+
+	// ConditionType identifies the kind of an order condition on the wire.
+	ConditionType int
+
+	// Conjunction joins an order condition to the condition that follows it.
+	Conjunction int
+
+	// PriceCondition is met when the price of a contract crosses a level.
+	PriceCondition struct {
+		Conjunction   Conjunction // How the condition joins the next one.
+		IsMore        bool        // Met when the price is above, rather than below, the level.
+		Price         float64     // The price level.
+		ContractId    int         // The contract id of the watched contract.
+		Exchange      string      // The exchange of the watched contract.
+		TriggerMethod int         // The price used to evaluate the condition, e.g. 2 for last price.
+	}
+
+	// TimeCondition is met when the time passes a point.
+	TimeCondition struct {
+		Conjunction Conjunction // How the condition joins the next one.
+		IsMore      bool        // Met after, rather than before, the time.
+		Time        string      // The time, as yyyymmdd hh:mm:ss with an optional time zone.
+	}
+
+	// MarginCondition is met when the account's margin cushion crosses a percentage.
+	MarginCondition struct {
+		Conjunction Conjunction // How the condition joins the next one.
+		IsMore      bool        // Met when the cushion is above, rather than below, the percentage.
+		Percent     int         // The margin cushion percentage.
+	}
+
+	// ExecutionCondition is met when a trade executes in a contract.
+	ExecutionCondition struct {
+		Conjunction  Conjunction // How the condition joins the next one.
+		SecurityType string      // The security type of the executed contract.
+		Exchange     string      // The exchange of the executed contract.
+		Symbol       string      // The symbol of the executed contract.
+	}
+
+	// VolumeCondition is met when the traded volume of a contract crosses a level.
+	VolumeCondition struct {
+		Conjunction Conjunction // How the condition joins the next one.
+		IsMore      bool        // Met when the volume is above, rather than below, the level.
+		Volume      int         // The volume level.
+		ContractId  int         // The contract id of the watched contract.
+		Exchange    string      // The exchange of the watched contract.
+	}
+
+	// PercentChangeCondition is met when the daily percentage change of a contract crosses a level.
+	PercentChangeCondition struct {
+		Conjunction   Conjunction // How the condition joins the next one.
+		IsMore        bool        // Met when the change is above, rather than below, the level.
+		ChangePercent float64     // The percentage change.
+		ContractId    int         // The contract id of the watched contract.
+		Exchange      string      // The exchange of the watched contract.
+	}
+
+	// Position is the position of an account in a contract.
+	Position struct {
+		Account  string   // The account holding the position.
+		Contract Contract // The position's contract.
+		Quantity float64  // The number of positions held, negative for short positions. Fractional for some instruments.
+		AvgCost  float64  // The average cost of the position.
+
+		ModelCode string // The model the position belongs to, only reported by PositionsMulti.
+	}
+
+	// AccountValue is a value of an account, e.g. its net liquidation or cash balance.
+	AccountValue struct {
+		Account   string // The account the value belongs to.
+		ModelCode string // The model the value belongs to, only reported by AccountValuesMulti.
+		Key       string // The name of the value, e.g. NetLiquidation.
+		Value     string // The value, usually a number.
+		Currency  string // The currency of the value, empty for values without a currency.
+	}
+
+	// PortfolioItem is a position of an account with its market value, as reported by AccountUpdates.
+	PortfolioItem struct {
+		Account       string   // The account holding the position.
+		Contract      Contract // The position's contract.
+		Position      float64  // The number of positions held, negative for short positions.
+		MarketPrice   float64  // The unit price of the instrument.
+		MarketValue   float64  // The total market value of the position.
+		AverageCost   float64  // The average cost of the position.
+		UnrealizedPnL float64  // The profit or loss of the open position.
+		RealizedPnL   float64  // The profit or loss of the closed part of the position.
+	}
+
+	// AccountUpdate is a notification of AccountUpdates. Exactly one of AccountValue, PortfolioItem, UpdateTime or DownloadEnd is set.
+	AccountUpdate struct {
+		AccountValue  *AccountValue  // A value of the account changed.
+		PortfolioItem *PortfolioItem // A position of the account changed.
+		UpdateTime    string         // The time of the last update, as hh:mm.
+		DownloadEnd   bool           // All the values and positions of the account have been sent.
+	}
+
+	// PnL is the daily profit and loss of an account or model.
+	PnL struct {
+		DailyPnL      float64 // The profit or loss of the day.
+		UnrealizedPnL float64 // The profit or loss of the open positions.
+		RealizedPnL   float64 // The profit or loss of the positions closed during the day.
+	}
+
+	// PnLSingle is the daily profit and loss of a single position.
+	PnLSingle struct {
+		Position      float64 // The number of positions held.
+		DailyPnL      float64 // The profit or loss of the day.
+		UnrealizedPnL float64 // The profit or loss of the open position.
+		RealizedPnL   float64 // The profit or loss of the part of the position closed during the day.
+		Value         float64 // The current market value of the position.
+	}
+
+	// FaGroup is a financial advisor group, a set of accounts orders are allocated to.
+	FaGroup struct {
+		Name          string   // The name of the group.
+		Accounts      []string // The accounts of the group.
+		DefaultMethod string   // The allocation method, e.g. EqualQuantity, NetLiq, AvailableEquity or PctChange.
+	}
+
+	// FaProfile is a financial advisor allocation profile, telling how orders are allocated to accounts.
+	FaProfile struct {
+		Name        string         // The name of the profile.
+		Type        int            // The allocation type, 1 for percentages, 2 for financial ratios, 3 for shares.
+		Allocations []FaAllocation // The allocations to the accounts.
+	}
+
+	// FaAllocation is the allocation to an account in a financial advisor profile.
+	FaAllocation struct {
+		Account string  `xml:"acct"`   // The account.
+		Amount  float64 `xml:"amount"` // The percentage, ratio or number of shares allocated to the account.
+	}
+
+	// FaAlias is the alias of an account.
+	FaAlias struct {
+		Account string `xml:"account"` // The account.
+		Alias   string `xml:"alias"`   // The alias of the account.
+	}
+
+	// faGroupsXml is the XML document of the financial advisor groups.
+	faGroupsXml struct {
+		XMLName xml.Name     `xml:"ListOfGroups"`
+		Groups  []faGroupXml `xml:"Group"`
+	}
+
+	faGroupXml struct {
+		Name          string        `xml:"name"`
+		Accounts      faAccountsXml `xml:"ListOfAccts"`
+		DefaultMethod string        `xml:"defaultMethod"`
+	}
+
+	faAccountsXml struct {
+		VarName  string   `xml:"varName,attr"`
+		Accounts []string `xml:"String"`
+	}
+
+	// faProfilesXml is the XML document of the financial advisor profiles.
+	faProfilesXml struct {
+		XMLName  xml.Name       `xml:"ListOfAllocationProfiles"`
+		Profiles []faProfileXml `xml:"AllocationProfile"`
+	}
+
+	faProfileXml struct {
+		Name        string           `xml:"name"`
+		Type        int              `xml:"type"`
+		Allocations faAllocationsXml `xml:"ListOfAllocations"`
+	}
+
+	faAllocationsXml struct {
+		VarName     string         `xml:"varName,attr"`
+		Allocations []FaAllocation `xml:"Allocation"`
+	}
+
+	// faAliasesXml is the XML document of the account aliases.
+	faAliasesXml struct {
+		XMLName xml.Name  `xml:"ListOfAccountAliases"`
+		Aliases []FaAlias `xml:"AccountAlias"`
+	}
+
+	// TickType identifies the kind of a market data tick, e.g. TickBid.
+	TickType int
+
+	// Tick is a market data tick, one of PriceTick, SizeTick, StringTick, GenericTick, EfpTick, OptionComputationTick, MarketDataTypeTick or RequestParametersTick.
+	Tick interface {
+		Type() TickType
+	}
+
+	// PriceTick is a price, e.g. the bid, with the size at that price when TWS reports it.
+	PriceTick struct {
+		TickType   TickType       // The kind of price, e.g. TickBid.
+		Price      float64        // The price, -1 when there is none, e.g. no bid.
+		Size       float64        // The size at the price, zero for prices without a size, e.g. the high.
+		Attributes TickAttributes // Attributes of the price.
+	}
+
+	// TickAttributes describe a price tick.
+	TickAttributes struct {
+		CanAutoExecute bool // The bid or ask is executable automatically.
+		PastLimit      bool // The bid is below the day's low, or the ask above the day's high.
+		PreOpen        bool // The bid or ask is reported during the pre-open session.
+	}
+
+	// SizeTick is a size, e.g. the bid size or the day's volume.
+	SizeTick struct {
+		TickType TickType // The kind of size, e.g. TickBidSize.
+		Size     float64  // The size.
+	}
+
+	// StringTick is a value reported as text, e.g. the last trade timestamp.
+	StringTick struct {
+		TickType TickType // The kind of value, e.g. TickLastTimestamp.
+		Value    string   // The value.
+	}
+
+	// GenericTick is a numeric value, e.g. the shortable indicator or the historical volatility.
+	GenericTick struct {
+		TickType TickType // The kind of value, e.g. TickShortable.
+		Value    float64  // The value.
+	}
+
+	// EfpTick is an exchange for physical computation.
+	EfpTick struct {
+		TickType                 TickType // The kind of computation, e.g. TickBidEfpComputation.
+		BasisPoints              float64  // The annualized basis points, a financing rate that can be compared to broker rates.
+		FormattedBasisPoints     string   // The annualized basis points as a percentage.
+		ImpliedFuturesPrice      float64  // The implied futures price.
+		HoldDays                 int      // The number of days the position is held until the futures expire.
+		FutureLastTradeDate      string   // The expiration date of the single stock future.
+		DividendImpact           float64  // The dividend impact upon the annualized basis points interest rate.
+		DividendsToLastTradeDate float64  // The dividends expected until the expiration of the single stock future.
+	}
+
+	// MarketDataType tells whether market data is live or delayed.
+	MarketDataType int
+
+	// MarketDataTypeTick reports the type of the ticks that follow it, e.g. delayed ticks when there is no market data subscription.
+	MarketDataTypeTick struct {
+		MarketDataType MarketDataType // The type of the market data.
+	}
+
+	// RequestParametersTick describes a market data request, it is sent before the first tick.
+	RequestParametersTick struct {
+		MinTick             float64 // The minimum price increment of the contract.
+		BboExchange         string  // The exchange whose SMART components map the exchange codes of the ticks, see ExchangeNames.
+		SnapshotPermissions int     // The snapshot permissions of the contract.
+	}
+
+	// ErrorTick ends a market data stream that failed, e.g. with error 200 when the contract is not found or 354 without a market data subscription.
+	ErrorTick struct {
+		Error RequestError // The error reported by TWS.
+	}
+
+	// OptionComputationTick holds the implied volatility, greeks and model price of an option.
+	// Values that could not be computed are reported as math.MaxFloat64.
+	OptionComputationTick struct {
+		TickType          TickType // The kind of computation, e.g. TickModelOptionComputation.
+		PriceBased        bool     // The computation is based on prices, rather than returns.
+		ImpliedVolatility float64  // The implied volatility calculated by the TWS option modeler, using the specified tick type value.
+		Delta             float64  // The option delta.
+		OptionPrice       float64  // The option price.
+		PvDividend        float64  // The present value of dividends expected on the option's underlying.
+		Gamma             float64  // The option gamma.
+		Vega              float64  // The option vega.
+		Theta             float64  // The option theta.
+		UnderlyingPrice   float64  // The price of the underlying.
+	}
+
+	// Quote is the current Level 1 state of a contract, folded from market data ticks.
+	// Prices and sizes not reported yet are zero, delayed ticks update the same fields as real time ones.
+	Quote struct {
+		Bid           float64   // The highest bid price, -1 when there is none.
+		BidSize       float64   // The size at the bid.
+		Ask           float64   // The lowest ask price, -1 when there is none.
+		AskSize       float64   // The size at the ask.
+		Last          float64   // The price of the last trade.
+		LastSize      float64   // The size of the last trade.
+		LastTimestamp time.Time // The time of the last trade.
+		Volume        float64   // The trading volume of the day.
+		Open          float64   // The opening price of the day.
+		High          float64   // The highest price of the day.
+		Low           float64   // The lowest price of the day.
+		Close         float64   // The closing price of the previous day.
+		Halted        bool      // Trading of the contract is halted.
+		Shortable     float64   // Above 2.5 when at least 1000 shares can be shorted, above 1.5 when shares may be located, 1.5 or less otherwise.
+
+		MarketDataType MarketDataType // The type of the market data, e.g. MarketDataDelayed.
+	}
+
+	// DepthOperation tells how a market depth event changes the order book.
+	DepthOperation int
+
+	// DepthEvent is a change of a row of the order book.
+	DepthEvent struct {
+		Position    int            // The row of the book changed, starting at 0.
+		MarketMaker string         // The exchange or market maker of the row, only reported by Level 2 depth.
+		Operation   DepthOperation // How the row changes, e.g. DepthInsert.
+		Side        int            // The side of the book, 0 for asks and 1 for bids.
+		Price       float64        // The price of the row.
+		Size        float64        // The size of the row.
+		SmartDepth  bool           // The row aggregates the depth of several exchanges.
+		Error       *RequestError  // The error ending the stream, set with the DepthError operation.
+	}
+
+	// OrderBook is the market depth of a contract, the best prices first.
+	OrderBook struct {
+		Bids []BookRow // The bid ladder.
+		Asks []BookRow // The ask ladder.
+	}
+
+	// BookRow is a price level of an order book.
+	BookRow struct {
+		Price       float64 // The price.
+		Size        float64 // The size at the price.
+		MarketMaker string  // The exchange or market maker of the row, only reported by Level 2 depth.
+	}
+
+	// DepthExchange is an exchange offering market depth.
+	DepthExchange struct {
+		Exchange        string // The exchange.
+		SecurityType    string // The security type of the depth offered.
+		ListingExchange string // The listing exchange.
+		ServiceDataType string // Deep for Level 2 depth, Deep2 for Level 2 depth with market makers.
+		AggregatedGroup int    // The group of exchanges aggregated by smart depth.
+	}
+
+	// SmartComponent is an exchange routed to by SMART, with the code identifying it in market data.
+	SmartComponent struct {
+		BitNumber      int    // The bit of the exchange in the SMART components mask.
+		Exchange       string // The name of the exchange, e.g. ARCA.
+		ExchangeLetter string // The code of the exchange in market data ticks, e.g. P.
+	}
+
+	// Duration is the time span covered by a historical data request, e.g. Days(5).
+	Duration struct {
+		count int
+		unit  string
+	}
+
+	// BarSize is the time span of a historical bar, e.g. BarSize1Min.
+	BarSize string
+
+	// WhatToShow is the kind of data of historical bars, e.g. WhatToShowTrades.
+	WhatToShow string
+
+	// AccountSummaryTag names a value reported by an account summary subscription.
+	AccountSummaryTag string
+
+	// AccountSummaryValue is a value of an account summary.
+	AccountSummaryValue struct {
+		Account  string            // The account the value belongs to.
+		Tag      AccountSummaryTag // The name of the value, e.g. NetLiquidation.
+		Value    string            // The value, usually a number.
+		Currency string            // The currency of the value, empty for values without a currency.
+	}
+)
+
+const (
+	PriceConditionType         ConditionType = 1
+	TimeConditionType          ConditionType = 3
+	MarginConditionType        ConditionType = 4
+	ExecutionConditionType     ConditionType = 5
+	VolumeConditionType        ConditionType = 6
+	PercentChangeConditionType ConditionType = 7
 )
+
+// Tick types reported by MarketData, see https://interactivebrokers.github.io/tws-api/tick_types.html.
+const (
+	TickBidSize                    TickType = 0
+	TickBid                        TickType = 1
+	TickAsk                        TickType = 2
+	TickAskSize                    TickType = 3
+	TickLast                       TickType = 4
+	TickLastSize                   TickType = 5
+	TickHigh                       TickType = 6
+	TickLow                        TickType = 7
+	TickVolume                     TickType = 8
+	TickClose                      TickType = 9
+	TickBidOptionComputation       TickType = 10
+	TickAskOptionComputation       TickType = 11
+	TickLastOptionComputation      TickType = 12
+	TickModelOptionComputation     TickType = 13
+	TickOpen                       TickType = 14
+	TickLow13Week                  TickType = 15
+	TickHigh13Week                 TickType = 16
+	TickLow26Week                  TickType = 17
+	TickHigh26Week                 TickType = 18
+	TickLow52Week                  TickType = 19
+	TickHigh52Week                 TickType = 20
+	TickAvgVolume                  TickType = 21
+	TickOpenInterest               TickType = 22
+	TickOptionHistoricalVolatility TickType = 23
+	TickOptionImpliedVolatility    TickType = 24
+	TickOptionBidExchange          TickType = 25
+	TickOptionAskExchange          TickType = 26
+	TickOptionCallOpenInterest     TickType = 27
+	TickOptionPutOpenInterest      TickType = 28
+	TickOptionCallVolume           TickType = 29
+	TickOptionPutVolume            TickType = 30
+	TickIndexFuturePremium         TickType = 31
+	TickBidExchange                TickType = 32
+	TickAskExchange                TickType = 33
+	TickAuctionVolume              TickType = 34
+	TickAuctionPrice               TickType = 35
+	TickAuctionImbalance           TickType = 36
+	TickMarkPrice                  TickType = 37
+	TickBidEfpComputation          TickType = 38
+	TickAskEfpComputation          TickType = 39
+	TickLastEfpComputation         TickType = 40
+	TickOpenEfpComputation         TickType = 41
+	TickHighEfpComputation         TickType = 42
+	TickLowEfpComputation          TickType = 43
+	TickCloseEfpComputation        TickType = 44
+	TickLastTimestamp              TickType = 45
+	TickShortable                  TickType = 46
+	TickFundamentalRatios          TickType = 47
+	TickRtVolume                   TickType = 48
+	TickHalted                     TickType = 49
+	TickBidYield                   TickType = 50
+	TickAskYield                   TickType = 51
+	TickLastYield                  TickType = 52
+	TickCustomOptionComputation    TickType = 53
+	TickTradeCount                 TickType = 54
+	TickTradeRate                  TickType = 55
+	TickVolumeRate                 TickType = 56
+	TickLastRthTrade               TickType = 57
+	TickRtHistoricalVolatility     TickType = 58
+	TickIbDividends                TickType = 59
+	TickBondFactorMultiplier       TickType = 60
+	TickRegulatoryImbalance        TickType = 61
+	TickNews                       TickType = 62
+	TickShortTermVolume3Minutes    TickType = 63
+	TickShortTermVolume5Minutes    TickType = 64
+	TickShortTermVolume10Minutes   TickType = 65
+	TickDelayedBid                 TickType = 66
+	TickDelayedAsk                 TickType = 67
+	TickDelayedLast                TickType = 68
+	TickDelayedBidSize             TickType = 69
+	TickDelayedAskSize             TickType = 70
+	TickDelayedLastSize            TickType = 71
+	TickDelayedHigh                TickType = 72
+	TickDelayedLow                 TickType = 73
+	TickDelayedVolume              TickType = 74
+	TickDelayedClose               TickType = 75
+	TickDelayedOpen                TickType = 76
+	TickRtTradeVolume              TickType = 77
+	TickCreditmanMarkPrice         TickType = 78
+	TickCreditmanSlowMarkPrice     TickType = 79
+	TickDelayedBidOption           TickType = 80
+	TickDelayedAskOption           TickType = 81
+	TickDelayedLastOption          TickType = 82
+	TickDelayedModelOption         TickType = 83
+	TickLastExchange               TickType = 84
+	TickLastRegulatoryTime         TickType = 85
+	TickFuturesOpenInterest        TickType = 86
+	TickAverageOptionVolume        TickType = 87
+	TickDelayedLastTimestamp       TickType = 88
+	TickShortableShares            TickType = 89
+	TickDelayedHalted              TickType = 90
+	TickReuters2MutualFunds        TickType = 91
+	TickEtfNavClose                TickType = 92
+	TickEtfNavPriorClose           TickType = 93
+	TickEtfNavBid                  TickType = 94
+	TickEtfNavAsk                  TickType = 95
+	TickEtfNavLast                 TickType = 96
+	TickEtfFrozenNavLast           TickType = 97
+	TickEtfNavHigh                 TickType = 98
+	TickEtfNavLow                  TickType = 99
+	TickSocialMarketAnalytics      TickType = 100
+	TickEstimatedIpoMidpoint       TickType = 101
+	TickFinalIpoLast               TickType = 102
+)
+
+// Types of the ticks that are not TWS tick types.
+const (
+	TickMarketDataType    TickType = -1 // The type of MarketDataTypeTick.
+	TickRequestParameters TickType = -2 // The type of RequestParametersTick.
+	TickError             TickType = -3 // The type of ErrorTick.
+)
+
+// Market data types, see SetMarketDataType.
+const (
+	MarketDataLive          MarketDataType = 1 // Real time data, requires a market data subscription.
+	MarketDataFrozen        MarketDataType = 2 // The last data recorded at the close of the market.
+	MarketDataDelayed       MarketDataType = 3 // Delayed data, 15 to 20 minutes, for contracts without a market data subscription.
+	MarketDataDelayedFrozen MarketDataType = 4 // The last delayed data recorded at the close of the market.
+)
+
+// tickTypeNames are the names of the tick types, indexed by tick type.
+var tickTypeNames = []string{
+	"BidSize",
+	"Bid",
+	"Ask",
+	"AskSize",
+	"Last",
+	"LastSize",
+	"High",
+	"Low",
+	"Volume",
+	"Close",
+	"BidOptionComputation",
+	"AskOptionComputation",
+	"LastOptionComputation",
+	"ModelOptionComputation",
+	"Open",
+	"Low13Week",
+	"High13Week",
+	"Low26Week",
+	"High26Week",
+	"Low52Week",
+	"High52Week",
+	"AvgVolume",
+	"OpenInterest",
+	"OptionHistoricalVolatility",
+	"OptionImpliedVolatility",
+	"OptionBidExchange",
+	"OptionAskExchange",
+	"OptionCallOpenInterest",
+	"OptionPutOpenInterest",
+	"OptionCallVolume",
+	"OptionPutVolume",
+	"IndexFuturePremium",
+	"BidExchange",
+	"AskExchange",
+	"AuctionVolume",
+	"AuctionPrice",
+	"AuctionImbalance",
+	"MarkPrice",
+	"BidEfpComputation",
+	"AskEfpComputation",
+	"LastEfpComputation",
+	"OpenEfpComputation",
+	"HighEfpComputation",
+	"LowEfpComputation",
+	"CloseEfpComputation",
+	"LastTimestamp",
+	"Shortable",
+	"FundamentalRatios",
+	"RtVolume",
+	"Halted",
+	"BidYield",
+	"AskYield",
+	"LastYield",
+	"CustomOptionComputation",
+	"TradeCount",
+	"TradeRate",
+	"VolumeRate",
+	"LastRthTrade",
+	"RtHistoricalVolatility",
+	"IbDividends",
+	"BondFactorMultiplier",
+	"RegulatoryImbalance",
+	"News",
+	"ShortTermVolume3Minutes",
+	"ShortTermVolume5Minutes",
+	"ShortTermVolume10Minutes",
+	"DelayedBid",
+	"DelayedAsk",
+	"DelayedLast",
+	"DelayedBidSize",
+	"DelayedAskSize",
+	"DelayedLastSize",
+	"DelayedHigh",
+	"DelayedLow",
+	"DelayedVolume",
+	"DelayedClose",
+	"DelayedOpen",
+	"RtTradeVolume",
+	"CreditmanMarkPrice",
+	"CreditmanSlowMarkPrice",
+	"DelayedBidOption",
+	"DelayedAskOption",
+	"DelayedLastOption",
+	"DelayedModelOption",
+	"LastExchange",
+	"LastRegulatoryTime",
+	"FuturesOpenInterest",
+	"AverageOptionVolume",
+	"DelayedLastTimestamp",
+	"ShortableShares",
+	"DelayedHalted",
+	"Reuters2MutualFunds",
+	"EtfNavClose",
+	"EtfNavPriorClose",
+	"EtfNavBid",
+	"EtfNavAsk",
+	"EtfNavLast",
+	"EtfFrozenNavLast",
+	"EtfNavHigh",
+	"EtfNavLow",
+	"SocialMarketAnalytics",
+	"EstimatedIpoMidpoint",
+	"FinalIpoLast",
+}
+
+// Market depth operations.
+const (
+	DepthInsert DepthOperation = 0  // A row is inserted at the position, moving the following rows down.
+	DepthUpdate DepthOperation = 1  // The row at the position is replaced.
+	DepthDelete DepthOperation = 2  // The row at the position is removed, moving the following rows up.
+	DepthReset  DepthOperation = -1 // Not a TWS operation: the book was reset by TWS and is cleared.
+	DepthError  DepthOperation = -2 // Not a TWS operation: the request failed, the last event of the stream.
+)
+
+// Order book sides.
+const (
+	DepthAsk = 0
+	DepthBid = 1
+)
+
+// Historical bar sizes.
+const (
+	BarSize1Sec   BarSize = "1 secs"
+	BarSize5Secs  BarSize = "5 secs"
+	BarSize10Secs BarSize = "10 secs"
+	BarSize15Secs BarSize = "15 secs"
+	BarSize30Secs BarSize = "30 secs"
+	BarSize1Min   BarSize = "1 min"
+	BarSize2Mins  BarSize = "2 mins"
+	BarSize3Mins  BarSize = "3 mins"
+	BarSize5Mins  BarSize = "5 mins"
+	BarSize10Mins BarSize = "10 mins"
+	BarSize15Mins BarSize = "15 mins"
+	BarSize20Mins BarSize = "20 mins"
+	BarSize30Mins BarSize = "30 mins"
+	BarSize1Hour  BarSize = "1 hour"
+	BarSize2Hours BarSize = "2 hours"
+	BarSize3Hours BarSize = "3 hours"
+	BarSize4Hours BarSize = "4 hours"
+	BarSize8Hours BarSize = "8 hours"
+	BarSize1Day   BarSize = "1 day"
+	BarSize1Week  BarSize = "1 week"
+	BarSize1Month BarSize = "1 month"
+)
+
+// Kinds of historical data.
+const (
+	WhatToShowTrades                  WhatToShow = "TRADES"
+	WhatToShowMidpoint                WhatToShow = "MIDPOINT"
+	WhatToShowBid                     WhatToShow = "BID"
+	WhatToShowAsk                     WhatToShow = "ASK"
+	WhatToShowBidAsk                  WhatToShow = "BID_ASK"
+	WhatToShowAdjustedLast            WhatToShow = "ADJUSTED_LAST"
+	WhatToShowHistoricalVolatility    WhatToShow = "HISTORICAL_VOLATILITY"
+	WhatToShowOptionImpliedVolatility WhatToShow = "OPTION_IMPLIED_VOLATILITY"
+	WhatToShowFeeRate                 WhatToShow = "FEE_RATE"
+	WhatToShowYieldBid                WhatToShow = "YIELD_BID"
+	WhatToShowYieldAsk                WhatToShow = "YIELD_ASK"
+	WhatToShowYieldBidAsk             WhatToShow = "YIELD_BID_ASK"
+	WhatToShowYieldLast               WhatToShow = "YIELD_LAST"
+)
+
+// barSizeSeconds holds the length of the bar sizes, and the longest duration TWS serves for each,
+// following the valid duration and bar size settings of the TWS historical data limitations.
+// Longer durations are rejected by TWS, e.g. 1 secs bars over a day fail with an invalid step error.
+// Durations of a month or more are accepted for daily and longer bars.
+var barSizeSeconds = map[BarSize]struct{ length, maxDuration int }{
+	BarSize1Sec:   {1, 1800},
+	BarSize5Secs:  {5, 3600},
+	BarSize10Secs: {10, 14400},
+	BarSize15Secs: {15, 14400},
+	BarSize30Secs: {30, 28800},
+	BarSize1Min:   {60, secondsPerDay},
+	BarSize2Mins:  {120, 2 * secondsPerDay},
+	BarSize3Mins:  {180, 7 * secondsPerDay},
+	BarSize5Mins:  {300, 7 * secondsPerDay},
+	BarSize10Mins: {600, 7 * secondsPerDay},
+	BarSize15Mins: {900, 7 * secondsPerDay},
+	BarSize20Mins: {1200, 7 * secondsPerDay},
+	BarSize30Mins: {1800, 31 * secondsPerDay},
+	BarSize1Hour:  {3600, 31 * secondsPerDay},
+	BarSize2Hours: {7200, 31 * secondsPerDay},
+	BarSize3Hours: {10800, 31 * secondsPerDay},
+	BarSize4Hours: {14400, 31 * secondsPerDay},
+	BarSize8Hours: {28800, 31 * secondsPerDay},
+	BarSize1Day:   {secondsPerDay, math.MaxInt32},
+	BarSize1Week:  {7 * secondsPerDay, math.MaxInt32},
+	BarSize1Month: {31 * secondsPerDay, math.MaxInt32},
+}
+
+const secondsPerDay = 86400
+
+// Tags accepted by AccountSummary.
+const (
+	AccountSummaryAccountType                 AccountSummaryTag = "AccountType"
+	AccountSummaryNetLiquidation              AccountSummaryTag = "NetLiquidation"
+	AccountSummaryTotalCashValue              AccountSummaryTag = "TotalCashValue"
+	AccountSummarySettledCash                 AccountSummaryTag = "SettledCash"
+	AccountSummaryAccruedCash                 AccountSummaryTag = "AccruedCash"
+	AccountSummaryBuyingPower                 AccountSummaryTag = "BuyingPower"
+	AccountSummaryEquityWithLoanValue         AccountSummaryTag = "EquityWithLoanValue"
+	AccountSummaryPreviousEquityWithLoanValue AccountSummaryTag = "PreviousEquityWithLoanValue"
+	AccountSummaryGrossPositionValue          AccountSummaryTag = "GrossPositionValue"
+	AccountSummaryRegTEquity                  AccountSummaryTag = "RegTEquity"
+	AccountSummaryRegTMargin                  AccountSummaryTag = "RegTMargin"
+	AccountSummarySMA                         AccountSummaryTag = "SMA"
+	AccountSummaryInitMarginReq               AccountSummaryTag = "InitMarginReq"
+	AccountSummaryMaintMarginReq              AccountSummaryTag = "MaintMarginReq"
+	AccountSummaryAvailableFunds              AccountSummaryTag = "AvailableFunds"
+	AccountSummaryExcessLiquidity             AccountSummaryTag = "ExcessLiquidity"
+	AccountSummaryCushion                     AccountSummaryTag = "Cushion"
+	AccountSummaryFullInitMarginReq           AccountSummaryTag = "FullInitMarginReq"
+	AccountSummaryFullMaintMarginReq          AccountSummaryTag = "FullMaintMarginReq"
+	AccountSummaryFullAvailableFunds          AccountSummaryTag = "FullAvailableFunds"
+	AccountSummaryFullExcessLiquidity         AccountSummaryTag = "FullExcessLiquidity"
+	AccountSummaryLookAheadNextChange         AccountSummaryTag = "LookAheadNextChange"
+	AccountSummaryLookAheadInitMarginReq      AccountSummaryTag = "LookAheadInitMarginReq"
+	AccountSummaryLookAheadMaintMarginReq     AccountSummaryTag = "LookAheadMaintMarginReq"
+	AccountSummaryLookAheadAvailableFunds     AccountSummaryTag = "LookAheadAvailableFunds"
+	AccountSummaryLookAheadExcessLiquidity    AccountSummaryTag = "LookAheadExcessLiquidity"
+	AccountSummaryHighestSeverity             AccountSummaryTag = "HighestSeverity"
+	AccountSummaryDayTradesRemaining          AccountSummaryTag = "DayTradesRemaining"
+	AccountSummaryLeverage                    AccountSummaryTag = "Leverage-S"
+	AccountSummaryLedger                      AccountSummaryTag = "$LEDGER"     // Cash balances in the base currency.
+	AccountSummaryLedgerAll                   AccountSummaryTag = "$LEDGER:ALL" // Cash balances in all currencies.
+)
+
+const (
+	ConjunctionAnd Conjunction = iota // The condition and the next one must both be met.
+	ConjunctionOr                     // Either the condition or the next one must be met.
+)
+
+func (e OrderError) Error() string {
+	return fmt.Sprintf("order %d: error %d: %s", e.OrderId, e.Code, e.Message)
+}
+
+func (e RequestError) Error() string {
+	return fmt.Sprintf("request %d: error %d: %s", e.RequestId, e.Code, e.Message)
+}
+
+// NewOrder returns an order with the optional prices unset. Unlike zero, unset prices are not sent to TWS.
+func NewOrder() Order {
+	return Order{
+		LimitPrice:      math.MaxFloat64,
+		AuxPrice:        math.MaxFloat64,
+		PercentOffset:   math.MaxFloat64,
+		Volatility:      math.MaxFloat64,
+		TrailStopPrice:  math.MaxFloat64,
+		TrailingPercent: math.MaxFloat64,
+		CashQuantity:    math.MaxFloat64,
+	}
+}
+
+// AccountSummaryLedgerCurrency is the tag for the cash balances in the given currency.
+func AccountSummaryLedgerCurrency(currency string) AccountSummaryTag {
+	return AccountSummaryTag("$LEDGER:" + currency)
+}
+
+// Algo describes the typed parameters of an IB algorithm, which are sent as the order's algo strategy and tag values.
+type Algo interface {
+	Strategy() string      // The algo strategy name, e.g. Adaptive.
+	TagValues() []TagValue // The algo parameters.
+	Validate() error       // Checks the parameters before the order is sent.
+}
+
+func (p AdaptiveParams) Strategy() string {
+	return "Adaptive"
+}
+
+func (p AdaptiveParams) TagValues() []TagValue {
+	return []TagValue{
+		{Tag: "adaptivePriority", Value: p.Priority},
+	}
+}
+
+func (p AdaptiveParams) Validate() error {
+	return validateChoice("adaptive priority", p.Priority, "Urgent", "Normal", "Patient")
+}
+
+func (p TwapParams) Strategy() string {
+	return "Twap"
+}
+
+func (p TwapParams) TagValues() []TagValue {
+	return []TagValue{
+		{Tag: "strategyType", Value: p.StrategyType},
+		{Tag: "startTime", Value: p.StartTime},
+		{Tag: "endTime", Value: p.EndTime},
+		{Tag: "allowPastEndTime", Value: formatAlgoBool(p.AllowPastEndTime)},
+	}
+}
+
+func (p TwapParams) Validate() error {
+	if err := validateChoice("twap strategy type", p.StrategyType, "Marketable", "Matching Midpoint", "Matching Same Side", "Matching Last"); err != nil {
+		return err
+	}
+
+	return validateAlgoTimes(p.StartTime, p.EndTime)
+}
+
+func (p VwapParams) Strategy() string {
+	return "Vwap"
+}
+
+func (p VwapParams) TagValues() []TagValue {
+	return []TagValue{
+		{Tag: "maxPctVol", Value: formatAlgoFloat(p.MaxPctVol)},
+		{Tag: "startTime", Value: p.StartTime},
+		{Tag: "endTime", Value: p.EndTime},
+		{Tag: "allowPastEndTime", Value: formatAlgoBool(p.AllowPastEndTime)},
+		{Tag: "noTakeLiq", Value: formatAlgoBool(p.NoTakeLiq)},
+	}
+}
+
+func (p VwapParams) Validate() error {
+	if p.MaxPctVol < 0.01 || p.MaxPctVol > 0.5 {
+		return fmt.Errorf("vwap max percentage of volume %v must be between 0.01 and 0.5", p.MaxPctVol)
+	}
+
+	return validateAlgoTimes(p.StartTime, p.EndTime)
+}
+
+func (p ArrivalPriceParams) Strategy() string {
+	return "ArrivalPx"
+}
+
+func (p ArrivalPriceParams) TagValues() []TagValue {
+	return []TagValue{
+		{Tag: "maxPctVol", Value: formatAlgoFloat(p.MaxPctVol)},
+		{Tag: "riskAversion", Value: p.RiskAversion},
+		{Tag: "startTime", Value: p.StartTime},
+		{Tag: "endTime", Value: p.EndTime},
+		{Tag: "forceCompletion", Value: formatAlgoBool(p.ForceCompletion)},
+		{Tag: "allowPastEndTime", Value: formatAlgoBool(p.AllowPastEndTime)},
+	}
+}
+
+func (p ArrivalPriceParams) Validate() error {
+	if p.MaxPctVol < 0.1 || p.MaxPctVol > 0.5 {
+		return fmt.Errorf("arrival price max percentage of volume %v must be between 0.1 and 0.5", p.MaxPctVol)
+	}
+
+	if err := validateChoice("arrival price risk aversion", p.RiskAversion, "Get Done", "Aggressive", "Neutral", "Passive"); err != nil {
+		return err
+	}
+
+	return validateAlgoTimes(p.StartTime, p.EndTime)
+}
+
+func validateChoice(name string, value string, choices ...string) error {
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid %s %q, expected one of %q", name, value, choices)
+}
+
+// validateAlgoTimes checks the optional algo start and end times are formatted as hh:mm:ss with an optional time zone, or as yyyymmdd-hh:mm:ss in UTC.
+func validateAlgoTimes(times ...string) error {
+	for _, value := range times {
+		if value == "" {
+			continue
+		}
+
+		if _, err := time.Parse("20060102-15:04:05", value); err == nil {
+			continue
+		}
+
+		if len(value) < 8 || (len(value) > 8 && value[8] != ' ') {
+			return fmt.Errorf("invalid algo time %q, expected hh:mm:ss with an optional time zone", value)
+		}
+
+		if _, err := time.Parse("15:04:05", value[:8]); err != nil {
+			return fmt.Errorf("invalid algo time %q, expected hh:mm:ss with an optional time zone", value)
+		}
+	}
+
+	return nil
+}
+
+// Condition is an order condition, one of PriceCondition, TimeCondition, MarginCondition, ExecutionCondition, VolumeCondition or PercentChangeCondition.
+type Condition interface {
+	Type() ConditionType
+	conjunction() Conjunction
+}
+
+func (c PriceCondition) Type() ConditionType         { return PriceConditionType }
+func (c TimeCondition) Type() ConditionType          { return TimeConditionType }
+func (c MarginCondition) Type() ConditionType        { return MarginConditionType }
+func (c ExecutionCondition) Type() ConditionType     { return ExecutionConditionType }
+func (c VolumeCondition) Type() ConditionType        { return VolumeConditionType }
+func (c PercentChangeCondition) Type() ConditionType { return PercentChangeConditionType }
+
+func (c PriceCondition) conjunction() Conjunction         { return c.Conjunction }
+func (c TimeCondition) conjunction() Conjunction          { return c.Conjunction }
+func (c MarginCondition) conjunction() Conjunction        { return c.Conjunction }
+func (c ExecutionCondition) conjunction() Conjunction     { return c.Conjunction }
+func (c VolumeCondition) conjunction() Conjunction        { return c.Conjunction }
+func (c PercentChangeCondition) conjunction() Conjunction { return c.Conjunction }
+
+func (t TickType) String() string {
+	switch t {
+	case TickMarketDataType:
+		return "MarketDataType"
+	case TickRequestParameters:
+		return "RequestParameters"
+	case TickError:
+		return "Error"
+	}
+	if t >= 0 && int(t) < len(tickTypeNames) {
+		return tickTypeNames[t]
+	}
+	return fmt.Sprintf("TickType(%d)", int(t))
+}
+
+func (t PriceTick) Type() TickType             { return t.TickType }
+func (t SizeTick) Type() TickType              { return t.TickType }
+func (t StringTick) Type() TickType            { return t.TickType }
+func (t GenericTick) Type() TickType           { return t.TickType }
+func (t EfpTick) Type() TickType               { return t.TickType }
+func (t OptionComputationTick) Type() TickType { return t.TickType }
+func (t MarketDataTypeTick) Type() TickType    { return TickMarketDataType }
+func (t RequestParametersTick) Type() TickType { return TickRequestParameters }
+func (t ErrorTick) Type() TickType             { return TickError }
+
+// belongsTo tells whether the update refers to the account. Updates without an account, e.g. the update time, belong to the subscribed account.
+func (u AccountUpdate) belongsTo(account string) bool {
+	switch {
+	case u.AccountValue != nil:
+		return u.AccountValue.Account == "" || u.AccountValue.Account == account
+	case u.PortfolioItem != nil:
+		return u.PortfolioItem.Account == "" || u.PortfolioItem.Account == account
+	default:
+		return true
+	}
+}
+
+// Update folds a tick into the quote. It reports whether the quote changed.
+func (q *Quote) Update(tick Tick) bool {
+	previous := *q
+
+	switch t := tick.(type) {
+	case PriceTick:
+		switch t.TickType {
+		case TickBid, TickDelayedBid:
+			q.Bid = t.Price
+			q.BidSize = updatedSize(q.BidSize, t.Size)
+		case TickAsk, TickDelayedAsk:
+			q.Ask = t.Price
+			q.AskSize = updatedSize(q.AskSize, t.Size)
+		case TickLast, TickDelayedLast:
+			q.Last = t.Price
+			q.LastSize = updatedSize(q.LastSize, t.Size)
+		case TickOpen, TickDelayedOpen:
+			q.Open = t.Price
+		case TickHigh, TickDelayedHigh:
+			q.High = t.Price
+		case TickLow, TickDelayedLow:
+			q.Low = t.Price
+		case TickClose, TickDelayedClose:
+			q.Close = t.Price
+		}
+	case SizeTick:
+		switch t.TickType {
+		case TickBidSize, TickDelayedBidSize:
+			q.BidSize = t.Size
+		case TickAskSize, TickDelayedAskSize:
+			q.AskSize = t.Size
+		case TickLastSize, TickDelayedLastSize:
+			q.LastSize = t.Size
+		case TickVolume, TickDelayedVolume:
+			q.Volume = t.Size
+		}
+	case StringTick:
+		switch t.TickType {
+		case TickLastTimestamp, TickDelayedLastTimestamp:
+			if seconds, err := strconv.ParseInt(t.Value, 10, 64); err == nil {
+				q.LastTimestamp = time.Unix(seconds, 0)
+			}
+		}
+	case MarketDataTypeTick:
+		q.MarketDataType = t.MarketDataType
+	case GenericTick:
+		switch t.TickType {
+		case TickHalted, TickDelayedHalted:
+			q.Halted = t.Value > 0
+		case TickShortable:
+			q.Shortable = t.Value
+		}
+	}
+
+	return *q != previous
+}
+
+// updatedSize returns the size reported with a price, keeping the current size when the price came without one.
+func updatedSize(current float64, size float64) float64 {
+	if size == 0 {
+		return current
+	}
+	return size
+}
+
+// Apply changes the order book with a market depth event. Positions beyond the end of the ladder are added at its end.
+func (b *OrderBook) Apply(event DepthEvent) {
+	switch event.Operation {
+	case DepthReset:
+		b.Bids, b.Asks = nil, nil
+		return
+	case DepthError:
+		return
+	}
+
+	ladder := &b.Asks
+	if event.Side == DepthBid {
+		ladder = &b.Bids
+	}
+
+	row := BookRow{Price: event.Price, Size: event.Size, MarketMaker: event.MarketMaker}
+	position := event.Position
+
+	switch event.Operation {
+	case DepthInsert:
+		if position >= len(*ladder) {
+			*ladder = append(*ladder, row)
+			return
+		}
+		*ladder = append(*ladder, BookRow{})
+		copy((*ladder)[position+1:], (*ladder)[position:])
+		(*ladder)[position] = row
+	case DepthUpdate:
+		if position >= len(*ladder) {
+			*ladder = append(*ladder, row)
+			return
+		}
+		(*ladder)[position] = row
+	case DepthDelete:
+		if position < len(*ladder) {
+			*ladder = append((*ladder)[:position], (*ladder)[position+1:]...)
+		}
+	}
+}
+
+// Copy returns a copy of the order book that does not change with the original.
+func (b OrderBook) Copy() OrderBook {
+	return OrderBook{
+		Bids: append([]BookRow(nil), b.Bids...),
+		Asks: append([]BookRow(nil), b.Asks...),
+	}
+}
+
+// Seconds is a duration of a number of seconds.
+func Seconds(count int) Duration {
+	return Duration{count: count, unit: "S"}
+}
+
+// Days is a duration of a number of days.
+func Days(count int) Duration {
+	return Duration{count: count, unit: "D"}
+}
+
+// Weeks is a duration of a number of weeks.
+func Weeks(count int) Duration {
+	return Duration{count: count, unit: "W"}
+}
+
+// Months is a duration of a number of months.
+func Months(count int) Duration {
+	return Duration{count: count, unit: "M"}
+}
+
+// Years is a duration of a number of years.
+func Years(count int) Duration {
+	return Duration{count: count, unit: "Y"}
+}
+
+// String formats the duration as sent to TWS, e.g. 5 D.
+func (d Duration) String() string {
+	return fmt.Sprintf("%d %s", d.count, d.unit)
+}
+
+// seconds returns the approximate length of the duration.
+func (d Duration) seconds() int {
+	switch d.unit {
+	case "S":
+		return d.count
+	case "D":
+		return d.count * secondsPerDay
+	case "W":
+		return d.count * 7 * secondsPerDay
+	case "M":
+		return d.count * 31 * secondsPerDay
+	default:
+		return d.count * 365 * secondsPerDay
+	}
+}
+
+// Validate checks that TWS serves bars of the size over the duration: the duration is at least one bar, and not longer than allowed for the bar size.
+func (b BarSize) Validate(duration Duration) error {
+	if duration.count <= 0 || duration.unit == "" {
+		return fmt.Errorf("invalid duration %q", duration)
+	}
+
+	limits, ok := barSizeSeconds[b]
+	if !ok {
+		return fmt.Errorf("invalid bar size %q", string(b))
+	}
+
+	if duration.seconds() < limits.length {
+		return fmt.Errorf("duration %s is shorter than a %s bar", duration, string(b))
+	}
+
+	if duration.seconds() > limits.maxDuration {
+		return fmt.Errorf("duration %s is too long for %s bars, at most %d seconds are served", duration, string(b), limits.maxDuration)
+	}
+
+	return nil
+}
+
+func formatAlgoBool(flag bool) string {
+	if flag {
+		return "1"
+	}
+	return "0"
+}
+
+func formatAlgoFloat(num float64) string {
+	return strconv.FormatFloat(num, 'f', -1, 64)
+}
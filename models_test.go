@@ -0,0 +1,130 @@
+package ibapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgoParams(t *testing.T) {
+	t.Run("adaptive", func(t *testing.T) {
+		params := AdaptiveParams{Priority: "Normal"}
+
+		assert.Nil(t, params.Validate())
+		assert.Equal(t, "Adaptive", params.Strategy())
+		assert.Equal(t, []TagValue{{Tag: "adaptivePriority", Value: "Normal"}}, params.TagValues())
+
+		assert.NotNil(t, AdaptiveParams{Priority: "normal"}.Validate())
+	})
+
+	t.Run("vwap", func(t *testing.T) {
+		params := VwapParams{
+			MaxPctVol:        0.2,
+			StartTime:        "09:00:00 US/Eastern",
+			EndTime:          "20220610-20:00:00",
+			AllowPastEndTime: true,
+			NoTakeLiq:        false,
+		}
+
+		assert.Nil(t, params.Validate())
+		assert.Equal(t, "Vwap", params.Strategy())
+		assert.Equal(t, []TagValue{
+			{Tag: "maxPctVol", Value: "0.2"},
+			{Tag: "startTime", Value: "09:00:00 US/Eastern"},
+			{Tag: "endTime", Value: "20220610-20:00:00"},
+			{Tag: "allowPastEndTime", Value: "1"},
+			{Tag: "noTakeLiq", Value: "0"},
+		}, params.TagValues())
+
+		assert.NotNil(t, VwapParams{MaxPctVol: 20}.Validate())
+		assert.NotNil(t, VwapParams{MaxPctVol: 0.2, StartTime: "9am"}.Validate())
+	})
+
+	t.Run("twap", func(t *testing.T) {
+		assert.Nil(t, TwapParams{StrategyType: "Marketable"}.Validate())
+		assert.NotNil(t, TwapParams{StrategyType: "Midpoint"}.Validate())
+	})
+
+	t.Run("arrival price", func(t *testing.T) {
+		params := ArrivalPriceParams{MaxPctVol: 0.1, RiskAversion: "Neutral", StartTime: "09:30:00", ForceCompletion: true}
+
+		assert.Nil(t, params.Validate())
+		assert.Equal(t, "ArrivalPx", params.Strategy())
+		assert.NotNil(t, ArrivalPriceParams{MaxPctVol: 0.1, RiskAversion: "Careful"}.Validate())
+	})
+}
+
+func TestQuoteUpdate(t *testing.T) {
+	quote := Quote{}
+
+	assert.True(t, quote.Update(PriceTick{TickType: TickBid, Price: 151.25, Size: 300}))
+	assert.True(t, quote.Update(PriceTick{TickType: TickDelayedAsk, Price: 151.5, Size: 200}))
+	assert.True(t, quote.Update(SizeTick{TickType: TickBidSize, Size: 400}))
+	assert.True(t, quote.Update(PriceTick{TickType: TickHigh, Price: 152}))
+	assert.True(t, quote.Update(StringTick{TickType: TickLastTimestamp, Value: "1654868101"}))
+	assert.True(t, quote.Update(GenericTick{TickType: TickHalted, Value: 1}))
+
+	assert.True(t, quote.Update(MarketDataTypeTick{MarketDataType: MarketDataDelayed}))
+
+	assert.False(t, quote.Update(SizeTick{TickType: TickBidSize, Size: 400}))
+	assert.False(t, quote.Update(GenericTick{TickType: TickRtHistoricalVolatility, Value: 0.2}))
+
+	assert.Equal(t, 151.25, quote.Bid)
+	assert.Equal(t, 400.0, quote.BidSize)
+	assert.Equal(t, 151.5, quote.Ask)
+	assert.Equal(t, 200.0, quote.AskSize)
+	assert.Equal(t, 152.0, quote.High)
+	assert.Equal(t, int64(1654868101), quote.LastTimestamp.Unix())
+	assert.True(t, quote.Halted)
+	assert.Equal(t, MarketDataDelayed, quote.MarketDataType)
+}
+
+func TestOrderBookApply(t *testing.T) {
+	book := OrderBook{}
+
+	book.Apply(DepthEvent{Position: 0, Operation: DepthInsert, Side: DepthBid, Price: 4100.25, Size: 10})
+	book.Apply(DepthEvent{Position: 0, Operation: DepthInsert, Side: DepthBid, Price: 4100.5, Size: 5})
+	book.Apply(DepthEvent{Position: 0, Operation: DepthInsert, Side: DepthAsk, Price: 4100.75, Size: 8, MarketMaker: "GLOBEX"})
+
+	assert.Equal(t, []BookRow{{Price: 4100.5, Size: 5}, {Price: 4100.25, Size: 10}}, book.Bids)
+	assert.Equal(t, []BookRow{{Price: 4100.75, Size: 8, MarketMaker: "GLOBEX"}}, book.Asks)
+
+	snapshot := book.Copy()
+
+	book.Apply(DepthEvent{Position: 1, Operation: DepthUpdate, Side: DepthBid, Price: 4100.25, Size: 12})
+	book.Apply(DepthEvent{Position: 0, Operation: DepthDelete, Side: DepthBid})
+
+	assert.Equal(t, []BookRow{{Price: 4100.25, Size: 12}}, book.Bids)
+	assert.Equal(t, []BookRow{{Price: 4100.5, Size: 5}, {Price: 4100.25, Size: 10}}, snapshot.Bids)
+
+	t.Run("ignores deletes beyond the ladder", func(t *testing.T) {
+		book.Apply(DepthEvent{Position: 5, Operation: DepthDelete, Side: DepthAsk})
+		assert.Len(t, book.Asks, 1)
+	})
+
+	t.Run("clears the book on reset", func(t *testing.T) {
+		book.Apply(DepthEvent{Operation: DepthReset})
+		assert.Empty(t, book.Bids)
+		assert.Empty(t, book.Asks)
+	})
+}
+
+func TestBarSizeValidate(t *testing.T) {
+	assert.Equal(t, "5 D", Days(5).String())
+
+	assert.Nil(t, BarSize1Min.Validate(Days(1)))
+	assert.Nil(t, BarSize5Secs.Validate(Seconds(3600)))
+	assert.Nil(t, BarSize1Hour.Validate(Months(1)))
+	assert.Nil(t, BarSize1Day.Validate(Years(10)))
+
+	assert.NotNil(t, BarSize1Sec.Validate(Days(1)), "too long for the bar size")
+	assert.NotNil(t, BarSize1Day.Validate(Seconds(3600)), "shorter than a bar")
+	assert.NotNil(t, BarSize("7 mins").Validate(Days(1)), "unknown bar size")
+	assert.NotNil(t, BarSize1Min.Validate(Duration{}), "missing duration")
+}
+
+func TestAccountUpdateBelongsTo(t *testing.T) {
+	assert.True(t, AccountUpdate{AccountValue: &AccountValue{Account: "DU1234"}}.belongsTo("DU1234"))
+	assert.False(t, AccountUpdate{PortfolioItem: &PortfolioItem{Account: "DU5678"}}.belongsTo("DU1234"))
+	assert.True(t, AccountUpdate{UpdateTime: "15:30"}.belongsTo("DU1234"))
+}
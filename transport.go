@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 )
 
 // TcpMessageBus implements the MessageBus over TCP
@@ -22,7 +23,7 @@ func (b *TcpMessageBus) Connect(host string, port int, clientId int) error {
 	b.clientId = clientId
 
 	var err error
-	b.socket, err = net.Dial("tcp", fmt.Sprintf("%s:%d", host, port))
+	b.socket, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		return fmt.Errorf("error dialing %s:%d: %w", host, port, err)
 	}